@@ -0,0 +1,164 @@
+package unamex
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// AllowEntry lets the usernames matching Pattern pass the validation
+// rules listed in Rules, such as a partner account named "support"
+// that the blacklist would reject.
+type AllowEntry struct {
+	// Pattern is a username, or a pattern in the syntax of
+	// Policy.Blacklist such as "glob:legacy.*". Matching is
+	// case-insensitive.
+	Pattern string `json:"pattern" yaml:"pattern"`
+
+	// Rules lists the rules the matching usernames may fail, such as
+	// RuleIntegrity or RuleRange. An empty list allows every rule.
+	Rules []string `json:"rules" yaml:"rules"`
+}
+
+// Allowlist is a compiled list of AllowEntry. It is immutable and safe
+// for concurrent use.
+type Allowlist struct {
+	entries  []AllowEntry
+	matchers []*Matcher
+}
+
+// NewAllowlist compiles the entries into an Allowlist. It fails if a
+// pattern does not compile.
+//
+// Example usage:
+//
+//	allow, err := unamex.NewAllowlist(
+//		unamex.AllowEntry{Pattern: "support", Rules: []string{unamex.RuleIntegrity}},
+//		unamex.AllowEntry{Pattern: "glob:ops?", Rules: []string{unamex.RuleRange}},
+//	)
+func NewAllowlist(entries ...AllowEntry) (*Allowlist, error) {
+	a := &Allowlist{entries: slices.Clone(entries)}
+	for _, e := range entries {
+		m, err := Policy{Blacklist: []string{e.Pattern}}.Matcher()
+		if err != nil {
+			return nil, err
+		}
+		a.matchers = append(a.matchers, m)
+	}
+	return a, nil
+}
+
+// Allows reports whether username may fail rule, and returns the
+// pattern of the first entry allowing it. A failure not tied to a rule
+// is allowed by entries without Rules only; pass "" for rule.
+func (a *Allowlist) Allows(username, rule string) (pattern string, ok bool) {
+	if a == nil {
+		return "", false
+	}
+	for i, e := range a.entries {
+		if len(e.Rules) > 0 && (rule == "" || !slices.Contains(e.Rules, rule)) {
+			continue
+		}
+		if _, ok := a.matchers[i].Match(username); ok {
+			return e.Pattern, true
+		}
+	}
+	return "", false
+}
+
+// bypass reports whether the failure err of username is allowed, and
+// returns the Bypass to record. Errors that are neither a
+// *ValidationError nor ErrInvalid, such as a failed lookup or a taken
+// username, are never allowed.
+func (a *Allowlist) bypass(username string, err error) (Bypass, bool) {
+	if a == nil {
+		return Bypass{}, false
+	}
+	if err == nil {
+		err = ErrInvalid
+	}
+
+	var rule string
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		rule = ve.Rule
+	} else if !errors.Is(err, ErrInvalid) {
+		return Bypass{}, false
+	}
+
+	pattern, ok := a.Allows(username, rule)
+	return Bypass{Rule: rule, Err: err, Pattern: pattern}, ok
+}
+
+// Bypass records a validation failure that an allowlist entry
+// overrode.
+type Bypass struct {
+	// Rule is the rule that failed, or "" for a validator that failed
+	// without an error.
+	Rule string
+
+	// Err is the failure that was overridden.
+	Err error
+
+	// Pattern is the pattern of the allowlist entry that allowed it.
+	Pattern string
+}
+
+// Result is the outcome of a validation with its audit trail.
+type Result struct {
+	// Err holds the failures, combined as by ValidateAll,
+	// or nil if the username is valid.
+	Err error
+
+	// Bypassed lists the failures overridden by the allowlist,
+	// in the order of the validators.
+	Bypassed []Bypass
+}
+
+// Valid reports whether the username passed validation,
+// possibly thanks to the allowlist.
+func (r Result) Valid() bool {
+	return r.Err == nil
+}
+
+// WithAllowlist sets the allowlist of the Identity: usernames matching
+// an entry pass the rules the entry lists, in Validate, ValidateAll,
+// ValidateContext and ValidateResult. Suggestions never use the
+// allowlist. A nil allowlist turns the overrides off.
+//
+// Example usage:
+//
+//	allow, _ := unamex.NewAllowlist(unamex.AllowEntry{
+//		Pattern: "billing", Rules: []string{unamex.RuleIntegrity},
+//	})
+//	u := unamex.New("billing").WithAllowlist(allow)
+//	res := u.ValidateResult(ctx)
+//	// res.Valid() is true, res.Bypassed[0].Rule is "integrity"
+func (u *Identity) WithAllowlist(a *Allowlist) *Identity {
+	u.allow = a
+	return u
+}
+
+// ValidateResult runs every validator, like ValidateAll, and also
+// reports the failures the allowlist overrode, for auditing.
+func (u *Identity) ValidateResult(ctx context.Context) Result {
+	return u.engine().ValidateResult(ctx, u.uname)
+}
+
+// ValidateResult checks name against every validator of the Engine,
+// as Identity.ValidateResult.
+func (e *Engine) ValidateResult(ctx context.Context, name string) Result {
+	var res Result
+	var errs []error
+	err := e.runAudit(ctx, name, func(err error) bool {
+		if err == nil {
+			err = ErrInvalid
+		}
+		errs = append(errs, err)
+		return true
+	}, func(b Bypass) {
+		res.Bypassed = append(res.Bypassed, b)
+	})
+	res.Err = errors.Join(append(errs, err)...)
+	return res
+}
@@ -0,0 +1,271 @@
+package unamex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// AvailabilityChecker reports whether a username is still free,
+// typically by looking it up in the user store.
+type AvailabilityChecker interface {
+	Available(ctx context.Context, username string) (bool, error)
+}
+
+// AvailabilityFunc adapts an ordinary function to the
+// AvailabilityChecker interface.
+//
+// Example usage:
+//
+//	checker := AvailabilityFunc(func(ctx context.Context, s string) (bool, error) {
+//		return db.UsernameFree(ctx, s)
+//	})
+type AvailabilityFunc func(ctx context.Context, username string) (bool, error)
+
+// Available calls f(ctx, username).
+func (f AvailabilityFunc) Available(ctx context.Context, username string) (bool, error) {
+	return f(ctx, username)
+}
+
+// BatchAvailabilityChecker checks many usernames in one round trip,
+// such as a single SELECT ... WHERE name IN (...) query. Taken returns
+// the subset of usernames that are already in use.
+//
+// SuggestAvailable uses the batch method of any AvailabilityChecker
+// that also implements this interface; wrap a batch-only
+// implementation with BatchChecker to pass it where an
+// AvailabilityChecker is expected.
+type BatchAvailabilityChecker interface {
+	Taken(ctx context.Context, usernames []string) ([]string, error)
+}
+
+// BatchChecker adapts a BatchAvailabilityChecker to an
+// AvailabilityChecker. The result also implements
+// BatchAvailabilityChecker, so SuggestAvailable keeps batching.
+func BatchChecker(b BatchAvailabilityChecker) AvailabilityChecker {
+	return batchChecker{b}
+}
+
+type batchChecker struct {
+	BatchAvailabilityChecker
+}
+
+// Available checks a single username with one Taken call.
+func (b batchChecker) Available(ctx context.Context, username string) (bool, error) {
+	taken, err := b.Taken(ctx, []string{username})
+	return len(taken) == 0, err
+}
+
+// MemoryChecker is an in-memory, map-backed AvailabilityChecker and
+// BatchAvailabilityChecker, mainly intended for tests. It is safe for
+// concurrent use.
+type MemoryChecker struct {
+	mu    sync.RWMutex
+	taken map[string]bool
+}
+
+// NewMemoryChecker returns a MemoryChecker reporting the given
+// usernames as taken.
+func NewMemoryChecker(taken ...string) *MemoryChecker {
+	m := &MemoryChecker{taken: make(map[string]bool, len(taken))}
+	return m.Add(taken...)
+}
+
+// Add marks usernames as taken.
+func (m *MemoryChecker) Add(usernames ...string) *MemoryChecker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range usernames {
+		m.taken[s] = true
+	}
+	return m
+}
+
+// Available reports whether username has not been added.
+func (m *MemoryChecker) Available(_ context.Context, username string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.taken[username], nil
+}
+
+// Taken returns the given usernames that have been added.
+func (m *MemoryChecker) Taken(_ context.Context, usernames []string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var taken []string
+	for _, s := range usernames {
+		if m.taken[s] {
+			taken = append(taken, s)
+		}
+	}
+	return taken, nil
+}
+
+// ErrSuggestionBudget is returned by SuggestAvailable when the attempt
+// budget runs out before enough usable suggestions were found.
+var ErrSuggestionBudget = errors.New("unamex: suggestion attempt budget exhausted")
+
+// WithMaxAttempts sets the number of candidates SuggestAvailable may
+// generate before giving up. A value of zero or less restores the
+// default budget of 100 attempts.
+func (u *Identity) WithMaxAttempts(n int) *Identity {
+	u.maxAttempts = max(n, 0)
+	return u
+}
+
+// SuggestAvailable generates n suggestions that pass every validator of
+// the Identity object and that checker reports as available. See
+// Engine.SuggestAvailable.
+func (u *Identity) SuggestAvailable(ctx context.Context, n int, checker AvailabilityChecker) ([]string, error) {
+	return u.engine().SuggestAvailable(ctx, u.uname, n, checker)
+}
+
+// SuggestAvailable keeps drawing candidates from the suggestor pool,
+// reshuffled by weight as in Suggest on every pass, until it has n distinct suggestions that
+// differ from name, pass every validator and are reported available by
+// checker. A nil checker skips the availability check. An empty name
+// has no suggestions.
+//
+// If checker also implements BatchAvailabilityChecker, candidates are
+// over-generated and checked in one Taken call per round instead of
+// one Available call each.
+//
+// Every generated candidate counts against the attempt budget set with
+// Identity.WithMaxAttempts. When the budget is exhausted, the usable
+// suggestions found so far are returned with an error wrapping
+// ErrSuggestionBudget. Errors from checker and the context are returned
+// as they occur, also with the suggestions found so far.
+func (e *Engine) SuggestAvailable(ctx context.Context, name string, n int, checker AvailabilityChecker) ([]string, error) {
+	if name == "" {
+		return nil, ctx.Err()
+	}
+	budget := e.maxAttempts
+	if budget <= 0 {
+		budget = maxSuggestAttempts
+	}
+	gen := &candidates{
+		engine: e,
+		name:   name,
+		budget: budget,
+		rnd:    e.rand(name),
+		pool:   slices.Clone(e.suggestor),
+		seen:   make(map[string]bool),
+	}
+
+	batch, isBatch := checker.(BatchAvailabilityChecker)
+
+	suggestions := make([]string, 0, max(n, 0))
+
+	for len(suggestions) < n {
+		want := 1
+		if isBatch {
+			want = (n - len(suggestions)) * batchOvergenerate
+		}
+
+		var round []string
+		for len(round) < want {
+			candidate, ok, err := gen.next(ctx)
+			if err != nil {
+				return suggestions, err
+			}
+			if !ok {
+				break
+			}
+			round = append(round, candidate)
+		}
+		if len(round) == 0 {
+			break
+		}
+
+		free, err := available(ctx, checker, batch, round)
+		if err != nil {
+			return suggestions, err
+		}
+		suggestions = append(suggestions, free[:min(len(free), n-len(suggestions))]...)
+	}
+
+	if len(suggestions) < n {
+		return suggestions, fmt.Errorf("%w: found %d of %d suggestions in %d attempts",
+			ErrSuggestionBudget, len(suggestions), n, gen.attempts)
+	}
+	return suggestions, nil
+}
+
+// available returns the candidates that are free according to checker,
+// in their original order. batch, if not nil, is checker's batch method.
+func available(ctx context.Context, checker AvailabilityChecker, batch BatchAvailabilityChecker, candidates []string) ([]string, error) {
+	if checker == nil {
+		return candidates, nil
+	}
+
+	if batch != nil {
+		taken, err := batch.Taken(ctx, candidates)
+		if err != nil {
+			return nil, err
+		}
+		set := make(map[string]bool, len(taken))
+		for _, s := range taken {
+			set[s] = true
+		}
+		return slices.DeleteFunc(candidates, func(s string) bool {
+			return set[s]
+		}), nil
+	}
+
+	free := candidates[:0]
+	for _, candidate := range candidates {
+		ok, err := checker.Available(ctx, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			free = append(free, candidate)
+		}
+	}
+	return free, nil
+}
+
+// candidates generates distinct, valid suggestions for name from the
+// engine's suggestor pool within an attempt budget.
+type candidates struct {
+	engine   *Engine
+	name     string
+	budget   int
+	attempts int
+	rnd      Rand
+	pool     []suggestor
+	drawn    int
+	seen     map[string]bool
+}
+
+// next returns the next usable candidate, or false once the budget
+// or the pool is exhausted.
+func (c *candidates) next(ctx context.Context) (string, bool, error) {
+	for c.attempts < c.budget && len(c.pool) > 0 {
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
+
+		// Reshuffle at the start of every pass over the pool
+		if c.drawn%len(c.pool) == 0 {
+			shuffleSuggestorsRand(c.rnd, c.pool)
+		}
+		suggestor := c.pool[c.drawn%len(c.pool)]
+		c.drawn++
+		c.attempts++
+
+		suggestion := suggestor.fn(c.rnd, c.name)
+
+		if c.seen[suggestion] {
+			continue
+		}
+		c.seen[suggestion] = true
+
+		if c.engine.isValid(ctx, c.name, suggestion) {
+			return suggestion, true, nil
+		}
+	}
+	return "", false, nil
+}
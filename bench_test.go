@@ -1,6 +1,8 @@
 package unamex
 
 import (
+	"context"
+	"math/rand/v2"
 	"testing"
 )
 
@@ -38,6 +40,24 @@ func BenchmarkP_Suggest(b *testing.B) {
 	})
 }
 
+func BenchmarkS_EngineSuggest(b *testing.B) {
+	e := New().Engine()
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		e.Suggest(ctx, username, 1)
+	}
+}
+
+func BenchmarkP_EngineSuggest(b *testing.B) {
+	e := New().Engine()
+	ctx := context.Background()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			e.Suggest(ctx, username, 1)
+		}
+	})
+}
+
 func BenchmarkS_dSuggestors(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		defaultSuggestors()
@@ -334,14 +354,14 @@ func BenchmarkP_SetSepWithRandomDigit(b *testing.B) {
 
 func BenchmarkS_shuffleSuggestors(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		shuffleSuggestors(defaultSuggestors())
+		shuffleSuggestorsRand(globalRand{}, defaultSuggestors())
 	}
 }
 
 func BenchmarkP_shuffleSuggestors(b *testing.B) {
 	b.RunParallel(func(p *testing.PB) {
 		for p.Next() {
-			shuffleSuggestors(defaultSuggestors())
+			shuffleSuggestorsRand(globalRand{}, defaultSuggestors())
 		}
 	})
 }
@@ -387,3 +407,81 @@ func BenchmarkP_isVowel(b *testing.B) {
 		}
 	})
 }
+
+func benchMatcher(b *testing.B) *Matcher {
+	p := DefaultPolicy()
+	p.Reserved = []string{"contains:admin", "prefix:acme", "suffix:bot", "glob:test*user"}
+	m, err := p.Matcher()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
+
+func BenchmarkS_Matcher(b *testing.B) {
+	m := benchMatcher(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match("sarah.adamsmith")
+	}
+}
+
+func BenchmarkP_Matcher(b *testing.B) {
+	m := benchMatcher(b)
+	b.ResetTimer()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			m.Match("sarah.adamsmith")
+		}
+	})
+}
+
+// benchSimilarIndex protects 100k generated usernames.
+func benchSimilarIndex(b *testing.B) *SimilarIndex {
+	r := rand.New(rand.NewPCG(1, 2))
+	names := make([]string, 100_000)
+	for i := range names {
+		n := make([]byte, 6+r.IntN(8))
+		for j := range n {
+			n[j] = byte('a' + r.IntN(26))
+		}
+		names[i] = string(n)
+	}
+	return NewSimilarIndex(DefaultPolicy(), names...)
+}
+
+func BenchmarkS_SimilarIndex(b *testing.B) {
+	x := benchSimilarIndex(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = x.FindSimilar(ctx, "sarah.adams", 1)
+	}
+}
+
+func BenchmarkP_SimilarIndex(b *testing.B) {
+	x := benchSimilarIndex(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, _ = x.FindSimilar(ctx, "sarah.adams", 1)
+		}
+	})
+}
+
+func BenchmarkS_ScoreValidator(b *testing.B) {
+	v := DefaultPolicy().ScoreValidator(50)
+	for i := 0; i < b.N; i++ {
+		_, _ = v("sarah.adams")
+	}
+}
+
+func BenchmarkP_ScoreValidator(b *testing.B) {
+	v := DefaultPolicy().ScoreValidator(50)
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, _ = v("sarah.adams")
+		}
+	})
+}
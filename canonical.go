@@ -0,0 +1,82 @@
+package unamex
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Canonicalization selects the optional steps of Policy.Canonicalize,
+// on top of Unicode NFKC normalization and case folding, which always
+// apply.
+type Canonicalization uint8
+
+const (
+	// IgnoreSeparators removes the separators of the policy, so that
+	// "john.smith" and "johnsmith" are the same username, as dots are
+	// in Gmail addresses.
+	IgnoreSeparators Canonicalization = 1 << iota
+	// IgnoreConfusables maps the username to its confusable Skeleton,
+	// so that "paypal" and "paypa1" are the same username.
+	IgnoreConfusables
+)
+
+// Canonicalize returns the canonical key of name under the policy's
+// Canonicalization: usernames with the same key are the same username.
+// Store the key in a column under a unique index, next to the username
+// as the user typed it, to make "John.Smith" and "john.smith" collide.
+//
+// The key is NFKC normalized and case folded, so "ＪＯＨＮ" and "john"
+// share it, then optionally stripped of separators and mapped to its
+// confusable skeleton. It is stable for a given Canonicalization and
+// version of Unicode; recompute the keys when changing either.
+//
+// Example usage:
+//
+//	p := unamex.DefaultPolicy()
+//	p.Canonicalization = unamex.IgnoreSeparators
+//	p.Canonicalize("John.Smith") // "johnsmith"
+func (p Policy) Canonicalize(name string) string {
+	if isASCII(name) {
+		b := make([]byte, 0, len(name))
+		for i := 0; i < len(name); i++ {
+			c := name[i]
+			if p.Canonicalization&IgnoreSeparators != 0 && p.isSeparator(c) {
+				continue
+			}
+			if 'A' <= c && c <= 'Z' {
+				c += asciiCaseOffset
+			}
+			b = append(b, c)
+		}
+		name = string(b)
+	} else {
+		// Folding may undo the normalization, as with "ǰ".
+		name = norm.NFKC.String(cases.Fold().String(norm.NFKC.String(name)))
+		if p.Canonicalization&IgnoreSeparators != 0 {
+			name = strings.Map(func(r rune) rune {
+				if r < 0x80 && p.isSeparator(byte(r)) {
+					return -1
+				}
+				return r
+			}, name)
+		}
+	}
+
+	if p.Canonicalization&IgnoreConfusables != 0 {
+		name = Skeleton(name)
+	}
+	return name
+}
+
+// Equivalent reports whether a and b are the same username under the
+// policy, that is whether they have the same Canonicalize key.
+//
+// Example usage:
+//
+//	p := unamex.DefaultPolicy()
+//	p.Equivalent("John.Smith", "john.smith") // true
+func (p Policy) Equivalent(a, b string) bool {
+	return a == b || p.Canonicalize(a) == p.Canonicalize(b)
+}
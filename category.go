@@ -0,0 +1,103 @@
+package unamex
+
+import "slices"
+
+// The categories of the built-in blacklist. Every built-in entry
+// belongs to exactly one of them.
+const (
+	// CategorySystem holds system roles and service accounts,
+	// such as "admin", "hostmaster" or "noreply".
+	CategorySystem = "system"
+	// CategoryRoutes holds the names of common pages and routes,
+	// such as "login", "checkout" or "dashboard".
+	CategoryRoutes = "routes"
+	// CategoryBrands holds brands and products, such as "facebook"
+	// or "github".
+	CategoryBrands = "brands"
+	// CategoryTest holds test and demo accounts, such as
+	// "ctxtestuser".
+	CategoryTest = "test"
+	// CategoryCommon holds the remaining weak or common usernames,
+	// such as "welcome" or "yourname".
+	CategoryCommon = "common"
+)
+
+// builtinCategory maps every entry of the built-in blacklist to its
+// category.
+var builtinCategory = func() map[string]string {
+	m := make(map[string]string, len(blacklist))
+	for _, w := range blacklist {
+		m[w] = CategoryCommon
+	}
+	for category, words := range blacklistCategories {
+		for _, w := range words {
+			m[w] = category
+		}
+	}
+	return m
+}()
+
+// Categories returns the names of the categories of the built-in
+// blacklist, sorted.
+func Categories() []string {
+	return []string{CategoryBrands, CategoryCommon, CategoryRoutes, CategorySystem, CategoryTest}
+}
+
+// CategoryWords returns the entries of the built-in blacklist in the
+// given category, sorted, or nil for an unknown category.
+func CategoryWords(category string) []string {
+	var words []string
+	for _, w := range blacklist {
+		if builtinCategory[w] == category {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// reservedWord is a blacklisted or reserved entry with the category
+// it comes from, or "" for an entry without a category.
+type reservedWord struct {
+	entry    string
+	category string
+}
+
+// reservedWords returns the blacklist and reserved entries of the
+// policy with their category: the built-in blacklist standing in for a
+// nil Blacklist, then Reserved, then the custom categories in the order
+// of their names, leaving out the disabled categories.
+func (p Policy) reservedWords() []reservedWord {
+	var list []reservedWord
+	if p.Blacklist == nil {
+		for _, w := range blacklist {
+			if c := builtinCategory[w]; !slices.Contains(p.DisabledCategories, c) {
+				list = append(list, reservedWord{entry: w, category: c})
+			}
+		}
+	} else {
+		for _, w := range p.Blacklist {
+			list = append(list, reservedWord{entry: w})
+		}
+	}
+	for _, w := range p.Reserved {
+		list = append(list, reservedWord{entry: w})
+	}
+	for _, c := range sortedKeys(p.Categories) {
+		if slices.Contains(p.DisabledCategories, c) {
+			continue
+		}
+		for _, w := range p.Categories[c] {
+			list = append(list, reservedWord{entry: w, category: c})
+		}
+	}
+	return list
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
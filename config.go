@@ -0,0 +1,760 @@
+package unamex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat identifies the encoding of a policy document.
+type ConfigFormat uint8
+
+const (
+	// FormatJSON decodes the document as JSON.
+	FormatJSON ConfigFormat = iota + 1
+	// FormatYAML decodes the document as YAML.
+	FormatYAML
+)
+
+// Config is the declarative form of a Policy, as read from a JSON or
+// YAML document by LoadConfig or LoadConfigFile. Omitted fields keep
+// the values of DefaultPolicy.
+//
+// Example document:
+//
+//	length:
+//	  min: 3
+//	  max: 15
+//	characters:
+//	  separators: "_-"
+//	  max_separators: 2
+//	blacklist:
+//	  builtin: true
+//	  words: [staff, moderator]
+//	  files: [blacklist.txt]
+//	reserved: [acme, acmepay]
+//	categories:
+//	  product: [acmecloud, acmedrive]
+//	profanity:
+//	  enabled: true
+//	  min_severity: strong
+//	suggestions:
+//	  count: 5
+//	  separator: "_"
+type Config struct {
+	Length      LengthConfig        `json:"length" yaml:"length"`
+	Characters  CharactersConfig    `json:"characters" yaml:"characters"`
+	Blacklist   BlacklistConfig     `json:"blacklist" yaml:"blacklist"`
+	Reserved    []string            `json:"reserved" yaml:"reserved"`
+	Categories  map[string][]string `json:"categories" yaml:"categories"`
+	Suggestions SuggestionsConfig   `json:"suggestions" yaml:"suggestions"`
+	Allowlist   []AllowEntry        `json:"allowlist" yaml:"allowlist"`
+	Profanity   ProfanityConfig     `json:"profanity" yaml:"profanity"`
+	Routes      RoutesConfig        `json:"routes" yaml:"routes"`
+	Canonical   CanonicalConfig     `json:"canonical" yaml:"canonical"`
+	MinScore    int                 `json:"min_score" yaml:"min_score"`
+
+	// blacklist holds the words read from Blacklist.Files.
+	blacklist []string
+}
+
+// LengthConfig bounds the length of usernames.
+type LengthConfig struct {
+	Min int `json:"min" yaml:"min"`
+	Max int `json:"max" yaml:"max"`
+}
+
+// CharactersConfig configures the allowed separators, digit rules
+// and Unicode support.
+type CharactersConfig struct {
+	Separators                 string `json:"separators" yaml:"separators"`
+	MaxSeparators              int    `json:"max_separators" yaml:"max_separators"`
+	AllowLeadingSeparator      bool   `json:"allow_leading_separator" yaml:"allow_leading_separator"`
+	AllowTrailingSeparator     bool   `json:"allow_trailing_separator" yaml:"allow_trailing_separator"`
+	AllowConsecutiveSeparators bool   `json:"allow_consecutive_separators" yaml:"allow_consecutive_separators"`
+	AllowDigitsOnly            bool   `json:"allow_digits_only" yaml:"allow_digits_only"`
+
+	Unicode           bool     `json:"unicode" yaml:"unicode"`
+	Scripts           []string `json:"scripts" yaml:"scripts"`
+	AllowMixedScripts bool     `json:"allow_mixed_scripts" yaml:"allow_mixed_scripts"`
+}
+
+// BlacklistConfig lists where blacklisted usernames come from.
+// Files are newline-delimited, with blank lines and lines starting
+// with '#' ignored. Relative paths are resolved against the directory
+// of the configuration file. Normalize lists the normalization steps
+// by name: "fold_case", "strip_separators", "leet" and
+// "collapse_repeats". DisabledCategories lists built-in categories,
+// such as "routes", or names of the categories section whose entries
+// are not rejected.
+type BlacklistConfig struct {
+	Builtin            bool     `json:"builtin" yaml:"builtin"`
+	Words              []string `json:"words" yaml:"words"`
+	Files              []string `json:"files" yaml:"files"`
+	Normalize          []string `json:"normalize" yaml:"normalize"`
+	DisabledCategories []string `json:"disabled_categories" yaml:"disabled_categories"`
+}
+
+// ProfanityConfig configures the profanity rule. MinSeverity is
+// "mild", "strong" or "severe", and defaults to "mild"; Languages
+// restricts the bundled terms to the given languages. Words are
+// rejected at any severity, Exceptions are innocent words that
+// contain a term.
+type ProfanityConfig struct {
+	Enabled     bool     `json:"enabled" yaml:"enabled"`
+	MinSeverity string   `json:"min_severity" yaml:"min_severity"`
+	Languages   []string `json:"languages" yaml:"languages"`
+	Words       []string `json:"words" yaml:"words"`
+	Exceptions  []string `json:"exceptions" yaml:"exceptions"`
+}
+
+// RoutesConfig configures the route collision rule. Patterns are
+// routes or http.ServeMux patterns; the built-in well-known URL
+// segments and reserved file names are rejected unless allowed.
+type RoutesConfig struct {
+	Enabled                bool     `json:"enabled" yaml:"enabled"`
+	Patterns               []string `json:"patterns" yaml:"patterns"`
+	AllowWellKnown         bool     `json:"allow_well_known" yaml:"allow_well_known"`
+	AllowReservedFilenames bool     `json:"allow_reserved_filenames" yaml:"allow_reserved_filenames"`
+}
+
+// CanonicalConfig selects the differences ignored by
+// Policy.Canonicalize on top of case and Unicode compatibility forms.
+type CanonicalConfig struct {
+	IgnoreSeparators  bool `json:"ignore_separators" yaml:"ignore_separators"`
+	IgnoreConfusables bool `json:"ignore_confusables" yaml:"ignore_confusables"`
+}
+
+// SuggestionsConfig configures suggestion generation.
+// Count is the number of suggestions callers should request, as
+// returned by Config.SuggestionCount; Separator, if set, is used by the default suggestors and must
+// be one of the allowed separators. Disabled and Weights name
+// default suggestors, as listed by SuggestorNames, to turn off or
+// reweight.
+type SuggestionsConfig struct {
+	Count     int            `json:"count" yaml:"count"`
+	Separator string         `json:"separator" yaml:"separator"`
+	Disabled  []string       `json:"disabled" yaml:"disabled"`
+	Weights   map[string]int `json:"weights" yaml:"weights"`
+}
+
+// ConfigError reports a problem in a policy document. Path is the
+// dotted location of the offending field, such as "length.min" or
+// "blacklist.files[1]"; Line and Column are 1-based and zero when
+// the position is unknown.
+type ConfigError struct {
+	Path   string
+	Line   int
+	Column int
+	Err    error
+}
+
+// Error returns the location and description of the problem.
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	b.WriteString("unamex: config")
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ": line %d", e.Line)
+	}
+	if e.Column > 0 {
+		fmt.Fprintf(&b, ", column %d", e.Column)
+	}
+	if e.Path != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Path)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// defaultConfig returns the Config equivalent to DefaultPolicy.
+func defaultConfig() *Config {
+	p := DefaultPolicy()
+	return &Config{
+		Length: LengthConfig{Min: p.MinLength, Max: p.MaxLength},
+		Characters: CharactersConfig{
+			Separators:    p.Separators,
+			MaxSeparators: p.MaxSeparators,
+		},
+		Blacklist: BlacklistConfig{Builtin: true},
+	}
+}
+
+// LoadConfigFile reads a policy document from path. The format is
+// chosen by the file extension: ".json" for JSON, ".yaml" or ".yml"
+// for YAML.
+func LoadConfigFile(path string) (*Config, error) {
+	var format ConfigFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = FormatJSON
+	case ".yaml", ".yml":
+		format = FormatYAML
+	default:
+		return nil, fmt.Errorf("unamex: config: unknown format of %q", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data, format, filepath.Dir(path))
+}
+
+// LoadConfig reads a policy document in the given format from r and
+// validates it. Relative blacklist files are resolved against the
+// current working directory.
+//
+// Example usage:
+//
+//	cfg, err := LoadConfig(strings.NewReader(`{"length": {"min": 3}}`), FormatJSON)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	u := cfg.Identity("gamer")
+func LoadConfig(r io.Reader, format ConfigFormat) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data, format, "")
+}
+
+// parseConfig decodes, validates and resolves a policy document.
+func parseConfig(data []byte, format ConfigFormat, dir string) (*Config, error) {
+	c := defaultConfig()
+
+	var locate func(path string) (line, col int)
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			return nil, jsonConfigError(data, dec, err)
+		}
+		// A document holds a single value.
+		end := int(dec.InputOffset())
+		var extra json.RawMessage
+		if err := dec.Decode(&extra); err != io.EOF {
+			if err != nil {
+				return nil, jsonConfigError(data, dec, err)
+			}
+			end += len(data[end:]) - len(bytes.TrimLeft(data[end:], " \t\r\n"))
+			line, col := lineColumn(data, end)
+			return nil, &ConfigError{Line: line, Column: col,
+				Err: errors.New("unexpected data after the document")}
+		}
+		locate = jsonLocator(data)
+	case FormatYAML:
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			return nil, yamlConfigError(&root, err)
+		}
+		if len(root.Content) > 0 {
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			if err := dec.Decode(c); err != nil {
+				return nil, yamlConfigError(&root, err)
+			}
+		}
+		locate = yamlLocator(&root)
+	default:
+		return nil, fmt.Errorf("unamex: config: unknown format %d", format)
+	}
+
+	if err := c.check(dir); err != nil {
+		var ce *ConfigError
+		if errors.As(err, &ce) {
+			ce.Line, ce.Column = locate(ce.Path)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// check validates the document and reads the blacklist files.
+func (c *Config) check(dir string) error {
+	fail := func(path, format string, args ...any) error {
+		return &ConfigError{Path: path, Err: fmt.Errorf(format, args...)}
+	}
+
+	if c.Length.Min < 1 {
+		return fail("length.min", "must be at least 1, got %d", c.Length.Min)
+	}
+	if c.Length.Max < c.Length.Min {
+		return fail("length.max", "must not be less than length.min (%d), got %d",
+			c.Length.Min, c.Length.Max)
+	}
+
+	for i := 0; i < len(c.Characters.Separators); i++ {
+		ch := c.Characters.Separators[i]
+		if ch <= ' ' || ch >= 0x7f || isLetter(ch) || isDigit(ch) {
+			return fail("characters.separators",
+				"%q is not a printable ASCII symbol", c.Characters.Separators[i:i+1])
+		}
+	}
+	if c.Characters.MaxSeparators < -1 {
+		return fail("characters.max_separators",
+			"must be -1 (no limit) or more, got %d", c.Characters.MaxSeparators)
+	}
+	for i, name := range c.Characters.Scripts {
+		if _, ok := unicode.Scripts[name]; !ok {
+			return fail(fmt.Sprintf("characters.scripts[%d]", i), "unknown script %q", name)
+		}
+	}
+	if len(c.Characters.Scripts) > 0 && !c.Characters.Unicode {
+		return fail("characters.scripts", "requires characters.unicode")
+	}
+
+	for i, w := range c.Blacklist.Words {
+		if strings.TrimSpace(w) == "" {
+			return fail(fmt.Sprintf("blacklist.words[%d]", i), "must not be empty")
+		}
+		if err := checkEntry(w); err != nil {
+			return &ConfigError{Path: fmt.Sprintf("blacklist.words[%d]", i), Err: err}
+		}
+	}
+	for i, name := range c.Blacklist.Normalize {
+		if !slices.Contains(normalizationNames[:], name) {
+			return fail(fmt.Sprintf("blacklist.normalize[%d]", i),
+				"unknown normalization %q", name)
+		}
+	}
+	for i, w := range c.Reserved {
+		if strings.TrimSpace(w) == "" {
+			return fail(fmt.Sprintf("reserved[%d]", i), "must not be empty")
+		}
+		if err := checkEntry(w); err != nil {
+			return &ConfigError{Path: fmt.Sprintf("reserved[%d]", i), Err: err}
+		}
+	}
+
+	for _, name := range sortedKeys(c.Categories) {
+		for i, w := range c.Categories[name] {
+			path := fmt.Sprintf("categories.%s[%d]", name, i)
+			if strings.TrimSpace(w) == "" {
+				return fail(path, "must not be empty")
+			}
+			if err := checkEntry(w); err != nil {
+				return &ConfigError{Path: path, Err: err}
+			}
+		}
+	}
+	for i, name := range c.Blacklist.DisabledCategories {
+		if _, ok := c.Categories[name]; !ok && !slices.Contains(Categories(), name) {
+			return fail(fmt.Sprintf("blacklist.disabled_categories[%d]", i),
+				"unknown category %q", name)
+		}
+	}
+
+	if c.Suggestions.Count < 0 {
+		return fail("suggestions.count", "must not be negative, got %d", c.Suggestions.Count)
+	}
+	if sep := c.Suggestions.Separator; sep != "" {
+		if len(sep) != 1 || !strings.Contains(c.Characters.Separators, sep) {
+			return fail("suggestions.separator",
+				"%q is not one of characters.separators %q", sep, c.Characters.Separators)
+		}
+	}
+	for i, name := range c.Suggestions.Disabled {
+		if !slices.Contains(SuggestorNames(), name) {
+			return fail(fmt.Sprintf("suggestions.disabled[%d]", i), "unknown suggestor %q", name)
+		}
+	}
+	for _, name := range sortedKeys(c.Suggestions.Weights) {
+		path := "suggestions.weights." + name
+		if !slices.Contains(SuggestorNames(), name) {
+			return fail(path, "unknown suggestor %q", name)
+		}
+		if w := c.Suggestions.Weights[name]; w < 0 {
+			return fail(path, "must not be negative, got %d", w)
+		}
+	}
+
+	for i, e := range c.Allowlist {
+		if strings.TrimSpace(e.Pattern) == "" {
+			return fail(fmt.Sprintf("allowlist[%d].pattern", i), "must not be empty")
+		}
+		if err := checkEntry(e.Pattern); err != nil {
+			return &ConfigError{Path: fmt.Sprintf("allowlist[%d].pattern", i), Err: err}
+		}
+	}
+
+	if s := c.Profanity.MinSeverity; s != "" {
+		if _, ok := parseSeverity(s); !ok {
+			return fail("profanity.min_severity", "unknown severity %q", s)
+		}
+	}
+	for i, lang := range c.Profanity.Languages {
+		if !slices.ContainsFunc(profanity, func(t ProfanityTerm) bool { return t.Lang == lang }) {
+			return fail(fmt.Sprintf("profanity.languages[%d]", i), "unknown language %q", lang)
+		}
+	}
+	for i, w := range c.Profanity.Words {
+		if strings.TrimSpace(w) == "" {
+			return fail(fmt.Sprintf("profanity.words[%d]", i), "must not be empty")
+		}
+	}
+	for i, w := range c.Profanity.Exceptions {
+		if strings.TrimSpace(w) == "" {
+			return fail(fmt.Sprintf("profanity.exceptions[%d]", i), "must not be empty")
+		}
+	}
+
+	if c.MinScore < 0 || c.MinScore > 100 {
+		return fail("min_score", "must be between 0 and 100, got %d", c.MinScore)
+	}
+
+	for i, pattern := range c.Routes.Patterns {
+		if _, err := routeSegment(pattern); err != nil {
+			return &ConfigError{Path: fmt.Sprintf("routes.patterns[%d]", i), Err: err}
+		}
+	}
+
+	c.blacklist = nil
+	for i, name := range c.Blacklist.Files {
+		if dir != "" && !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		words, err := readWordFile(name)
+		if err != nil {
+			return &ConfigError{Path: fmt.Sprintf("blacklist.files[%d]", i), Err: err}
+		}
+		for _, w := range words {
+			if err := checkEntry(w); err != nil {
+				return &ConfigError{Path: fmt.Sprintf("blacklist.files[%d]", i), Err: err}
+			}
+		}
+		c.blacklist = append(c.blacklist, words...)
+	}
+
+	return nil
+}
+
+// Policy returns the Policy described by the document.
+func (c *Config) Policy() Policy {
+	p := Policy{
+		MinLength:                  c.Length.Min,
+		MaxLength:                  c.Length.Max,
+		Separators:                 c.Characters.Separators,
+		MaxSeparators:              c.Characters.MaxSeparators,
+		AllowLeadingSeparator:      c.Characters.AllowLeadingSeparator,
+		AllowTrailingSeparator:     c.Characters.AllowTrailingSeparator,
+		AllowConsecutiveSeparators: c.Characters.AllowConsecutiveSeparators,
+		AllowDigitsOnly:            c.Characters.AllowDigitsOnly,
+		Unicode:                    c.Characters.Unicode,
+		Scripts:                    c.Characters.Scripts,
+		AllowMixedScripts:          c.Characters.AllowMixedScripts,
+		Reserved:                   c.Reserved,
+		Categories:                 c.Categories,
+		DisabledCategories:         c.Blacklist.DisabledCategories,
+		MinScore:                   c.MinScore,
+	}
+	for _, name := range c.Blacklist.Normalize {
+		p.Normalization |= 1 << slices.Index(normalizationNames[:], name)
+	}
+
+	if c.Profanity.Enabled {
+		p.Profanity = &Profanity{
+			Languages:  c.Profanity.Languages,
+			Exceptions: c.Profanity.Exceptions,
+		}
+		p.Profanity.MinSeverity, _ = parseSeverity(c.Profanity.MinSeverity)
+		for _, w := range c.Profanity.Words {
+			p.Profanity.Terms = append(p.Profanity.Terms,
+				ProfanityTerm{Term: w, Severity: SeveritySevere})
+		}
+	}
+
+	if c.Canonical.IgnoreSeparators {
+		p.Canonicalization |= IgnoreSeparators
+	}
+	if c.Canonical.IgnoreConfusables {
+		p.Canonicalization |= IgnoreConfusables
+	}
+	if c.Routes.Enabled {
+		p.Routes = &Routes{
+			Patterns:               c.Routes.Patterns,
+			AllowWellKnown:         c.Routes.AllowWellKnown,
+			AllowReservedFilenames: c.Routes.AllowReservedFilenames,
+		}
+	}
+
+	// With the built-in blacklist, the custom words are reserved on top
+	// of it, so that it keeps its categories.
+	if c.Blacklist.Builtin {
+		if len(c.Blacklist.Words) > 0 || len(c.blacklist) > 0 {
+			p.Reserved = append(slices.Clip(p.Reserved), c.Blacklist.Words...)
+			p.Reserved = append(p.Reserved, c.blacklist...)
+		}
+	} else {
+		p.Blacklist = []string{}
+		p.Blacklist = append(p.Blacklist, c.Blacklist.Words...)
+		p.Blacklist = append(p.Blacklist, c.blacklist...)
+	}
+
+	return p
+}
+
+// Identity returns a new Identity configured by the document.
+// The optional username behaves as in New.
+func (c *Config) Identity(username ...string) *Identity {
+	u := NewWithPolicy(c.Policy(), username...)
+	if sep := c.Suggestions.Separator; sep != "" {
+		u.suggestor = suggestorsFor(sep[0])
+	}
+	u.DisableSuggestors(c.Suggestions.Disabled...)
+	for name, weight := range c.Suggestions.Weights {
+		u.WithSuggestorWeight(name, weight)
+	}
+	// The allowlist was checked by LoadConfig; an invalid one set
+	// by hand is left out, which only makes validation stricter.
+	if len(c.Allowlist) > 0 {
+		if allow, err := NewAllowlist(c.Allowlist...); err == nil {
+			u.WithAllowlist(allow)
+		}
+	}
+	return u
+}
+
+// SuggestionCount returns the number of suggestions to request from
+// the Identity of the document, to pass as n to Suggest and friends:
+// suggestions.count, or 10 when it is not set.
+//
+// Example usage:
+//
+//	u := cfg.Identity("gamer_tag")
+//	suggestions := u.Suggest(cfg.SuggestionCount())
+func (c *Config) SuggestionCount() int {
+	if c.Suggestions.Count > 0 {
+		return c.Suggestions.Count
+	}
+	return numSuggestions
+}
+
+// jsonConfigError converts a JSON decoding error into a *ConfigError
+// carrying the line and column of the problem.
+func jsonConfigError(data []byte, dec *json.Decoder, err error) error {
+	offset := dec.InputOffset()
+	path := ""
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset counts the offending byte as read.
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		path = typeErr.Field
+		if line, col := jsonLocator(data)(path); line > 0 {
+			return &ConfigError{Path: path, Line: line, Column: col, Err: err}
+		}
+		offset = typeErr.Offset
+	}
+
+	line, col := lineColumn(data, int(offset))
+	return &ConfigError{Path: path, Line: line, Column: col, Err: err}
+}
+
+// jsonLocator returns a function mapping a field path to the 1-based
+// line and column of the field in the JSON document.
+func jsonLocator(data []byte) func(path string) (int, int) {
+	offsets := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// start skips the whitespace and punctuation between the end of
+	// the previous token and the start of the next one.
+	start := func(offset int) int {
+		for offset < len(data) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+			offset++
+		}
+		return offset
+	}
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				at := start(int(dec.InputOffset()))
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child := fmt.Sprint(key)
+				if path != "" {
+					child = path + "." + child
+				}
+				offsets[child] = at
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				child := path + "[" + strconv.Itoa(i) + "]"
+				offsets[child] = start(int(dec.InputOffset()))
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	_ = walk("")
+
+	return func(path string) (int, int) {
+		offset, ok := offsets[path]
+		if !ok {
+			return 0, 0
+		}
+		return lineColumn(data, offset)
+	}
+}
+
+// yamlErrorLine matches the line of a YAML error, and the field of an
+// unknown field error.
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (?:field (\S+) not found)?`)
+
+// yamlConfigError converts a YAML decoding error into a *ConfigError
+// carrying the line of the problem, and the path and column of the
+// offending field when root holds the parsed document.
+func yamlConfigError(root *yaml.Node, err error) error {
+	msg := err.Error()
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	m := yamlErrorLine.FindStringSubmatch(msg)
+	if m == nil {
+		return &ConfigError{Err: err}
+	}
+	line, _ := strconv.Atoi(m[1])
+	path, node := yamlNodeAt(root, "", line, m[2])
+	if node == nil {
+		return &ConfigError{Line: line, Err: err}
+	}
+	return &ConfigError{Path: path, Line: line, Column: node.Column, Err: err}
+}
+
+// yamlNodeAt returns the first node of the document under node, and
+// its path, that is a value on line, or the key named key if not
+// empty.
+func yamlNodeAt(node *yaml.Node, path string, line int, key string) (string, *yaml.Node) {
+	// A block collection starts on the line of its first entry, which
+	// is looked at instead.
+	isValue := func(v *yaml.Node) bool {
+		return key == "" && v.Line == line &&
+			(v.Kind == yaml.ScalarNode || v.Style&yaml.FlowStyle != 0)
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			return yamlNodeAt(node.Content[0], path, line, key)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			child := k.Value
+			if path != "" {
+				child = path + "." + child
+			}
+			if key != "" && k.Line == line && k.Value == key {
+				return child, k
+			}
+			if isValue(v) {
+				return child, v
+			}
+			if p, n := yamlNodeAt(v, child, line, key); n != nil {
+				return p, n
+			}
+		}
+	case yaml.SequenceNode:
+		for i, v := range node.Content {
+			child := path + "[" + strconv.Itoa(i) + "]"
+			if isValue(v) {
+				return child, v
+			}
+			if p, n := yamlNodeAt(v, child, line, key); n != nil {
+				return p, n
+			}
+		}
+	}
+	return "", nil
+}
+
+// yamlLocator returns a function mapping a field path to the 1-based
+// line and column of the field in the YAML document.
+func yamlLocator(root *yaml.Node) func(path string) (int, int) {
+	return func(path string) (int, int) {
+		node := root
+		if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+			node = node.Content[0]
+		}
+
+		line, col := 0, 0
+		for _, part := range strings.Split(path, ".") {
+			key, index := part, -1
+			if i := strings.IndexByte(part, '['); i >= 0 {
+				key = part[:i]
+				index, _ = strconv.Atoi(strings.TrimSuffix(part[i+1:], "]"))
+			}
+
+			var value *yaml.Node
+			for i := 0; node.Kind == yaml.MappingNode && i+1 < len(node.Content); i += 2 {
+				if k := node.Content[i]; k.Value == key {
+					line, col = k.Line, k.Column
+					value = node.Content[i+1]
+					break
+				}
+			}
+			if value == nil {
+				return line, col
+			}
+			if index >= 0 {
+				if value.Kind != yaml.SequenceNode || index >= len(value.Content) {
+					return line, col
+				}
+				value = value.Content[index]
+				line, col = value.Line, value.Column
+			}
+			node = value
+		}
+		return line, col
+	}
+}
+
+// lineColumn converts a byte offset in data into a 1-based
+// line and column.
+func lineColumn(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := 1 + bytes.Count(data[:offset], []byte{'\n'})
+	col := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, col
+}
@@ -0,0 +1,215 @@
+package unamex
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps characters to the Latin prototype they can be
+// mistaken for. It is the subset of the Unicode confusables data
+// (confusables.txt of Unicode Technical Standard #39) whose prototypes
+// are Latin letters, written in lowercase. Skeleton looks characters
+// up before folding their case, as some capitals look like another
+// letter than their lowercase form does. Since usernames are compared
+// case-insensitively, 'i', the lowercase of 'I', shares the prototype
+// "l" of 'I' and '1'.
+var confusables = map[rune]string{
+	// ASCII
+	'0': "o", '1': "l", '|': "l", 'i': "l", 'm': "rn",
+
+	// Latin
+	'ı': "l", 'ȷ': "j", 'ɑ': "a", 'ɡ': "g", 'ɩ': "l", 'ɪ': "l",
+	'ʏ': "y", 'ᴏ': "o", 'ℓ': "l", 'ƅ': "b", 'ǀ': "l",
+
+	// Greek
+	'α': "a", 'β': "b", 'γ': "y", 'ε': "e", 'ζ': "z", 'η': "n",
+	'ι': "l", 'κ': "k", 'μ': "u", 'ν': "v", 'ο': "o", 'ρ': "p",
+	'τ': "t", 'υ': "u", 'χ': "x", 'ω': "w", 'ϲ': "c", 'ϳ': "j",
+	'Ι': "l", 'Η': "h", 'Μ': "rn", 'Ν': "n", 'Υ': "y",
+
+	// Cyrillic
+	'а': "a", 'в': "b", 'г': "r", 'е': "e", 'к': "k", 'м': "rn",
+	'н': "h", 'о': "o", 'п': "n", 'р': "p", 'с': "c", 'т': "t",
+	'у': "y", 'х': "x", 'ѕ': "s", 'і': "l", 'ј': "j", 'ԁ': "d",
+	'ԛ': "q", 'ԝ': "w", 'ӏ': "l", 'ү': "y", 'һ': "h", 'ь': "b",
+
+	// Armenian
+	'օ': "o", 'ս': "u", 'հ': "h", 'ո': "n", 'ց': "g", 'զ': "q",
+}
+
+// isIgnorable reports whether r is an invisible character that
+// Skeleton drops, such as a zero width space or a soft hyphen.
+func isIgnorable(r rune) bool {
+	switch r {
+	case '\u00ad', '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+		return true
+	}
+	return false
+}
+
+// Skeleton returns the confusable skeleton of s: two usernames that
+// look alike, such as "paypa1", "PaypaI" and "paypal", or "аdmin"
+// written with a Cyrillic "а" and "admin", have the same skeleton.
+//
+// The skeleton follows the algorithm of Unicode Technical Standard #39,
+// with the compatibility decomposition (NFKD) so that fullwidth and
+// styled letters are covered too, and with case folded after the
+// mapping, since usernames are compared case-insensitively. Skeletons
+// are meant to be compared with each other, not displayed.
+//
+// Example usage:
+//
+//	unamex.Skeleton("PaypaI") == unamex.Skeleton("paypal") // true
+//	unamex.Skeleton("rnoree") == unamex.Skeleton("moree")  // true
+func Skeleton(s string) string {
+	if isASCII(s) {
+		var b strings.Builder
+		b.Grow(len(s) + 2)
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if p, ok := confusables[rune(c)]; ok {
+				b.WriteString(p)
+				continue
+			}
+			if 'A' <= c && c <= 'Z' {
+				c += asciiCaseOffset
+				if p, ok := confusables[rune(c)]; ok {
+					b.WriteString(p)
+					continue
+				}
+			}
+			b.WriteByte(c)
+		}
+		return b.String()
+	}
+
+	s = norm.NFKD.String(s)
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if isIgnorable(r) {
+			continue
+		}
+		if p, ok := confusables[r]; ok {
+			b.WriteString(p)
+			continue
+		}
+		r = unicode.ToLower(r)
+		if p, ok := confusables[r]; ok {
+			b.WriteString(p)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFD.String(b.String())
+}
+
+// ProtectedNames looks up existing usernames that new ones must not
+// impersonate, such as those of staff or verified accounts, by their
+// Skeleton. A user store would typically keep the skeleton of every
+// protected username in an indexed column.
+type ProtectedNames interface {
+	// LookupSkeleton returns a protected username whose skeleton is
+	// skeleton, with ok set to false when there is none.
+	LookupSkeleton(ctx context.Context, skeleton string) (name string, ok bool, err error)
+}
+
+// ProtectedSet is an in-memory ProtectedNames. It is safe for
+// concurrent use.
+type ProtectedSet struct {
+	mu    sync.RWMutex
+	names map[string]string
+}
+
+// NewProtectedSet returns a ProtectedSet holding the given usernames.
+func NewProtectedSet(names ...string) *ProtectedSet {
+	p := &ProtectedSet{names: make(map[string]string, len(names))}
+	return p.Add(names...)
+}
+
+// Add protects usernames. A username whose skeleton is already
+// protected is ignored.
+func (p *ProtectedSet) Add(names ...string) *ProtectedSet {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range names {
+		key := Skeleton(s)
+		if _, ok := p.names[key]; !ok {
+			p.names[key] = s
+		}
+	}
+	return p
+}
+
+// LookupSkeleton returns the protected username with the given skeleton.
+func (p *ProtectedSet) LookupSkeleton(_ context.Context, skeleton string) (string, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	name, ok := p.names[skeleton]
+	return name, ok, nil
+}
+
+// ConfusableValidator returns a validator rejecting usernames whose
+// Skeleton matches that of an exact blacklisted or reserved word of
+// the policy, or that of a username known to protected, which may be nil.
+// A username is not confusable with itself: a protected username
+// equal to the one validated, ignoring case, is not reported, except
+// when filtering suggestions, which never return a protected name.
+//
+// The failure is a *ValidationError with Rule RuleConfusable, Code
+// CodeConfusable and the name it was mistaken for in Match.
+//
+// Example usage:
+//
+//	staff := unamex.NewProtectedSet("moree", "support.team")
+//	u := unamex.New("rnoree").AddValidatorCtx(
+//		unamex.DefaultPolicy().ConfusableValidator(staff))
+//	err := u.ValidateContext(ctx) // username looks like "moree"
+func (p Policy) ConfusableValidator(protected ProtectedNames) ValidatorCtx {
+	words := p.words()
+	skeletons := make(map[string]string, len(words))
+	for _, w := range words {
+		mode, text := parseEntry(w)
+		if mode != MatchExact {
+			continue
+		}
+		key := Skeleton(text)
+		if _, ok := skeletons[key]; !ok {
+			skeletons[key] = w
+		}
+	}
+
+	return func(ctx context.Context, s string) error {
+		key := Skeleton(s)
+		if w, ok := skeletons[key]; ok {
+			return &ValidationError{
+				Rule: RuleConfusable, Code: CodeConfusable, Pos: -1, Match: w,
+			}
+		}
+		if protected == nil {
+			return nil
+		}
+
+		name, ok, err := protected.LookupSkeleton(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ok && (isSuggestion(ctx) || !strings.EqualFold(name, s)) {
+			return &ValidationError{
+				Rule: RuleConfusable, Code: CodeConfusable, Pos: -1, Match: name,
+			}
+		}
+		return nil
+	}
+}
+
+// WithConfusables adds the ConfusableValidator of the Identity's
+// policy to its context-aware validators. It is run by ValidateContext
+// and filters the suggestions.
+func (u *Identity) WithConfusables(protected ProtectedNames) *Identity {
+	return u.AddValidatorCtx(u.policy.ConfusableValidator(protected))
+}
@@ -6,4 +6,8 @@ const (
 	separator      = '.'
 	vowels         = "aeiouAEIOU"
 	numSuggestions = 10
+	// default attempt budget of SuggestAvailable
+	maxSuggestAttempts = 100
+	// candidates generated per missing suggestion in batch mode
+	batchOvergenerate = 3
 )
@@ -0,0 +1,80 @@
+package unamex
+
+import (
+	"context"
+	"slices"
+)
+
+// ValidatorCtx is a context-aware validation rule for checks that
+// perform I/O, such as looking the username up in a database.
+// It returns nil if the username passes and an error describing the
+// failure otherwise. Implementations should honor ctx cancellation
+// and deadlines.
+//
+// Example:
+//
+//	func availability(ctx context.Context, username string) error {
+//	    taken, err := db.UsernameTaken(ctx, username)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    if taken {
+//	        return errors.New("this username is unavailable")
+//	    }
+//	    return nil
+//	}
+type ValidatorCtx func(ctx context.Context, username string) error
+
+// ContextValidator adapts a Validator to a ValidatorCtx. The returned
+// rule ignores the context; a Validator that fails without an error
+// reports ErrInvalid.
+func ContextValidator(v Validator) ValidatorCtx {
+	return func(_ context.Context, username string) error {
+		if ok, err := v(username); !ok {
+			if err == nil {
+				err = ErrInvalid
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// AddValidatorCtx appends context-aware validators to the Identity
+// object. They run after the plain validators on every later call of
+// Validate, ValidateContext, ValidateAll and Suggest.
+func (u *Identity) AddValidatorCtx(validators ...ValidatorCtx) *Identity {
+	u.validatorCtx = append(u.validatorCtx, validators...)
+	return u
+}
+
+// ValidateContext is like Validate but passes ctx to the context-aware
+// validators and stops with the context's error as soon as ctx is done.
+// The given validators apply to this call only.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+//	defer cancel()
+//	err := New("myUsername").ValidateContext(ctx, availability)
+func (u *Identity) ValidateContext(ctx context.Context, validators ...ValidatorCtx) error {
+	e := u.engine()
+	if len(validators) > 0 {
+		e.validatorCtx = append(slices.Clip(e.validatorCtx), validators...)
+	}
+
+	return e.Validate(ctx, u.uname)
+}
+
+// SuggestContext is like Suggest but passes ctx to the context-aware
+// validators checking each suggestion. If ctx is done, it returns the
+// suggestions gathered so far with the context's error.
+// The given suggestors apply to this call only.
+func (u *Identity) SuggestContext(ctx context.Context, capacity int, suggestors ...Suggestor) ([]string, error) {
+	e := u.engine()
+	if len(suggestors) > 0 {
+		e.suggestor = append(slices.Clip(e.suggestor), fromSuggestors(suggestors)...)
+	}
+
+	return e.Suggest(ctx, u.uname, capacity)
+}
@@ -15,9 +15,18 @@
 //	}
 package unamex
 
+import (
+	"context"
+	"slices"
+)
+
 // Identity represents the core structure for username validation
 // and suggestion. It provides mechanisms to validate usernames
 // against custom rules and generate alternative suggestions.
+//
+// Identity is a convenience wrapper for a single goroutine: it holds
+// the current username and a mutable configuration. To share a
+// configuration across goroutines, build an Engine with Identity.Engine.
 type Identity struct {
 	// uname is the username being validated or used for suggestions.
 	uname string
@@ -27,11 +36,39 @@ type Identity struct {
 	// username, such as length, format, etc.
 	validator []Validator
 
+	// validatorCtx is a slice of context-aware validators run after
+	// the validators above, for rules that perform I/O.
+	validatorCtx []ValidatorCtx
+
 	// suggestor is a slice of Suggestor functions used to generate
 	// alternative username suggestions. These functions define
 	// the strategies for creating suggestions, such as adding prefixes
 	// or modifying vowels.
-	suggestor []Suggestor
+	suggestor []suggestor
+
+	// rnd is the source of random choices of the suggestors;
+	// nil means the global source.
+	rnd Rand
+
+	// hashKey and hashSalt enable deterministic suggestions drawn
+	// from a HashRand seeded with the username; nil means off.
+	hashKey  []byte
+	hashSalt string
+
+	// maxAttempts bounds the number of candidates SuggestAvailable
+	// may generate; zero means the default budget.
+	maxAttempts int
+
+	// policy holds the length and format rules the built-in
+	// validators and suggestors were configured with.
+	policy Policy
+
+	// allow lets matching usernames pass failing rules; nil means none.
+	allow *Allowlist
+
+	// rank orders the suggestions of SuggestDetailed; nil means
+	// RankByScore.
+	rank RankFunc
 }
 
 // Suggestor is a function type used to define strategies
@@ -45,16 +82,62 @@ type Identity struct {
 //	}
 type Suggestor func(s string) string
 
+// suggestor is the internal form of a Suggestor, with the name it is
+// reported and configured under. Its function draws its random choices
+// from r, so that they can be made reproducible with WithRand.
+type suggestor struct {
+	name string
+
+	// weight is the relative likelihood of the suggestor being tried
+	// first; off and a zero weight leave it out of the pool.
+	weight int
+	off    bool
+
+	fn func(r Rand, s string) string
+}
+
+// customSuggestor is the name of the suggestors added as Suggestor
+// functions.
+const customSuggestor = "custom"
+
+// fromSuggestors wraps Suggestor functions, which make their own
+// random choices, into the internal form.
+func fromSuggestors(suggestors []Suggestor) []suggestor {
+	if len(suggestors) == 0 {
+		return nil
+	}
+	list := make([]suggestor, len(suggestors))
+	for i, f := range suggestors {
+		list[i] = suggestor{name: customSuggestor, weight: 1, fn: func(_ Rand, s string) string { return f(s) }}
+	}
+	return list
+}
+
 // New creates a new Identity instance with default validator and suggestors.
 // An optional username can be provided as an argument.
 // If a username is provided, it will be set as the uname of the new Identity instance.
 // If no username is provided, the 'uname' field will be set to "default".
 // You can also set or change the uname later using the 'On' method.
 func New(username ...string) *Identity {
+	return NewWithPolicy(DefaultPolicy(), username...)
+}
+
+// NewWithPolicy creates a new Identity instance whose built-in validators
+// and suggestors follow the given policy instead of the default one.
+// The optional username behaves as in New.
+//
+// Example usage:
+//
+//	p := DefaultPolicy()
+//	p.MinLength, p.MaxLength = 3, 15
+//	p.Separators = "_-"
+//	u := NewWithPolicy(p, "gamer_tag")
+func NewWithPolicy(p Policy, username ...string) *Identity {
 	u := &Identity{
 		uname:     "default",
-		validator: defaultValidator(),
-		suggestor: defaultSuggestors(),
+		validator: p.Validators(),
+		suggestor: suggestorsFor(p.separator()),
+		policy:    p,
 	}
 
 	if len(username) > 0 {
@@ -89,84 +172,78 @@ func (u *Identity) On(username string) *Identity {
 //	// the default suggestors with your own
 //	u := New("myUsername").WithSuggestor(mySuggestor)
 func (u *Identity) WithSuggestor(suggestors ...Suggestor) *Identity {
-	u.suggestor = suggestors
+	u.suggestor = fromSuggestors(suggestors)
 	return u
 }
 
-// Suggest first checks if there are any suggestors passed as input.
-// If there are, it appends them to the suggestor field of the Identity struct.
-// It then shuffles the suggestors in the suggestor field.
-// The method creates a slice of strings with a length equal to the capacity input.
-// It then iterates over the suggestors up to the capacity input.
+// Suggest tries the suggestors of the Identity object, followed by
+// the given suggestors, in random order, up to the capacity input.
+// The given suggestors apply to this call only; use AddSuggestor to
+// keep a suggestor for later calls.
 // The capacity will be adjusted to match the number of available suggestors
 // if it initially exceeds that number.
-// For each iteration, it calls the suggestor with the 'uname' field of the Identity
+// For each suggestor, it calls the suggestor with the 'uname' field of the Identity
 // struct as input. If the suggestion is valid according to all validators
 // in the validator field of the Identity struct, it adds the suggestion
 // to the suggestions slice. The method returns the suggestions slice.
+//
+// Identity is not safe for concurrent use; share an Engine instead.
 func (u *Identity) Suggest(capacity int, suggestors ...Suggestor) []string {
+	e := u.engine()
 	if len(suggestors) > 0 {
-		u.suggestor = append(u.suggestor, suggestors...)
-	}
-
-	if capacity > len(u.suggestor) {
-		capacity = len(u.suggestor)
-	}
-
-	shuffleSuggestors(u.suggestor)
-
-	suggestions := make([]string, 0, capacity)
-
-	seen := make(map[string]bool)
-
-	var suggestor Suggestor
-
-	var suggestion string
-
-	for i := 0; i < capacity; i++ {
-		suggestor = u.suggestor[i]
-		suggestion = suggestor(u.uname)
-
-		if !u.isValid(suggestion) {
-			continue
-		}
-
-		if !seen[suggestion] {
-			suggestions = append(suggestions, suggestion)
-			seen[suggestion] = true
-		}
+		e.suggestor = append(slices.Clip(e.suggestor), fromSuggestors(suggestors)...)
 	}
 
+	suggestions, _ := e.Suggest(context.Background(), u.uname, capacity)
 	return suggestions
 }
 
-// defaultSuggestors returns a slice of default Suggestor functions.
-// Each Suggestor implements a unique strategy to generate alternative
+// AddSuggestor appends suggestors to the Identity object, so they
+// are used by every later call of Suggest.
+//
+// Example usage:
+//
+//	u := New("myUsername").AddSuggestor(func(s string) string {
+//		return s + "_hq"
+//	})
+func (u *Identity) AddSuggestor(suggestors ...Suggestor) *Identity {
+	u.suggestor = append(u.suggestor, fromSuggestors(suggestors)...)
+	return u
+}
+
+// defaultSuggestors returns a slice of default suggestor functions.
+// Each suggestor implements a unique strategy to generate alternative
 // usernames by modifying the input username in various ways.
-func defaultSuggestors() []Suggestor {
-	var suggestors = []Suggestor{
-		func(s string) string { return SetPrefixRandomDigit(s) },
-		func(s string) string { return SetSuffixRandomDigit(s) },
-		func(s string) string { return SetSepWithRandomDigit(s) },
+func defaultSuggestors() []suggestor {
+	return suggestorsFor(separator)
+}
+
+// suggestorsFor returns the default suggestor functions using
+// separator as the separator character, each with a weight of 1.
+func suggestorsFor(separator byte) []suggestor {
+	var suggestors = []suggestor{
+		{name: "SetPrefixRandomDigit", weight: 1, fn: func(r Rand, s string) string { return SetPrefixRandomDigitRand(r, s) }},
+		{name: "SetSuffixRandomDigit", weight: 1, fn: func(r Rand, s string) string { return SetSuffixRandomDigitRand(r, s) }},
+		{name: "SetSepWithRandomDigit", weight: 1, fn: func(r Rand, s string) string { return setSepWithRandomDigit(r, s, separator) }},
 
-		func(s string) string { return SetPenultimateSep(s, separator) },
-		func(s string) string { return SetPostInitialSep(s, separator) },
+		{name: "SetPenultimateSep", weight: 1, fn: func(_ Rand, s string) string { return SetPenultimateSep(s, separator) }},
+		{name: "SetPostInitialSep", weight: 1, fn: func(_ Rand, s string) string { return SetPostInitialSep(s, separator) }},
 
-		func(s string) string { return SetPenultimateSepDigit(s, separator) },
-		func(s string) string { return SetPostInitialSepDigit(s, separator) },
+		{name: "SetPenultimateSepDigit", weight: 1, fn: func(r Rand, s string) string { return SetPenultimateSepDigitRand(r, s, separator) }},
+		{name: "SetPostInitialSepDigit", weight: 1, fn: func(r Rand, s string) string { return SetPostInitialSepDigitRand(r, s, separator) }},
 
-		func(s string) string { return SwapTwoChars(s) },
+		{name: "SwapTwoChars", weight: 1, fn: func(_ Rand, s string) string { return SwapTwoChars(s) }},
 
-		func(s string) string { return RepeatPrefix(s) },
-		func(s string) string { return RepeatSuffix(s) },
-		func(s string) string { return RepeatSubfix(s) },
-		func(s string) string { return RepeatVowel(s) },
-		func(s string) string { return RepeatInitialAppendDigit(s, 10) },
+		{name: "RepeatPrefix", weight: 1, fn: func(_ Rand, s string) string { return RepeatPrefix(s) }},
+		{name: "RepeatSuffix", weight: 1, fn: func(_ Rand, s string) string { return RepeatSuffix(s) }},
+		{name: "RepeatSubfix", weight: 1, fn: func(_ Rand, s string) string { return RepeatSubfix(s) }},
+		{name: "RepeatVowel", weight: 1, fn: func(r Rand, s string) string { return RepeatVowelRand(r, s) }},
+		{name: "RepeatInitialAppendDigit", weight: 1, fn: func(r Rand, s string) string { return RepeatInitialAppendDigitRand(r, s, 10) }},
 
-		func(s string) string { return AlphabetTransform(s, alphabetSwap) },
-		func(s string) string { return VowelTransform(s, vowelSwap) },
+		{name: "AlphabetTransform", weight: 1, fn: func(_ Rand, s string) string { return AlphabetTransform(s, alphabetSwap) }},
+		{name: "VowelTransform", weight: 1, fn: func(r Rand, s string) string { return VowelTransformRand(r, s, vowelSwap) }},
 
-		func(s string) string { return VanishVowel(s) },
+		{name: "VanishVowel", weight: 1, fn: func(r Rand, s string) string { return VanishVowelRand(r, s) }},
 	}
 
 	return suggestors
@@ -97,3 +97,192 @@ var blacklist = []string{
 	"works", "workspace", "xentest", "yourdomain", "yourname", "yoursite",
 	"yourusername",
 }
+
+// blacklistCategories sorts the built-in blacklist into categories;
+// entries not listed here are in CategoryCommon.
+var blacklistCategories = map[string][]string{
+	CategorySystem: {
+		"abuse", "admin", "administrator", "adminuser", "admuser", "anonymous",
+		"besadmin", "citrixuser", "dbmsuser", "dbuser", "dtadmin", "founder",
+		"ftpuser", "guest", "helpassistant", "helpdesk", "hostmaster", "httpd",
+		"ithelpdesk", "itsupport", "localadmin", "mailer", "majordomo",
+		"manager", "master", "netuser", "noreply", "official", "operator",
+		"owner", "postfix", "postmaster", "poweruser", "scanuser",
+		"servicedesk", "soporte", "sqlservice", "ssladmin",
+		"ssladministrator", "sslwebmaster", "staff", "suporte", "support",
+		"sysadm", "sysadmin", "sysadministrator", "system", "sysuser",
+		"tempadmin", "vmwareuser", "vpnuser", "webadmin", "webmaster", "wheel",
+	},
+	CategoryRoutes: {
+		"about", "account", "activate", "analytics", "archive", "blogs",
+		"bookmark", "cache", "calendar", "cancel", "captcha", "career",
+		"changelog", "checkout", "compare", "config", "contact", "contactus",
+		"create", "dashboard", "default", "delete", "download", "explore",
+		"favorite", "feedback", "feeds", "files", "follow", "forgot", "forum",
+		"homepage", "howto", "https", "icons", "image", "index", "invitations",
+		"invoices", "login", "logout", "media", "messenger", "myaccount",
+		"newsletter", "notification", "notify", "oauth", "openid", "pages",
+		"password", "payment", "photo", "plugin", "portal", "posts", "privacy",
+		"privacypolicy", "profile", "public", "readme", "recovery", "register",
+		"registration", "release", "remove", "replies", "report",
+		"repositories", "request", "reset", "search", "secure", "session",
+		"setting", "setup", "share", "signin", "signout", "signup", "sitemap",
+		"static", "stats", "status", "style", "styleguide", "stylesheet",
+		"subscribe", "subscriptions", "template", "theme", "unfollow",
+		"unsubscribe", "update", "upload", "username", "users", "video",
+	},
+	CategoryBrands: {
+		"android", "aspnet", "citrix", "facebook", "github", "instagram",
+		"iphone", "javascript", "linux", "mysql", "oracle", "phpmyadmin",
+		"phppgadmin", "phpredisadmin", "python", "twitter", "websense",
+		"wordpress",
+	},
+	CategoryTest: {
+		"adtestuser", "appstest", "appstestpc", "atest", "audittest", "avtest",
+		"batchtest", "bestest", "blackberrytest", "btest", "citrixdemo",
+		"citrixdemotest", "citrixtest", "corptest", "corptestuser", "crmtest",
+		"ctest", "ctxtest", "ctxtestuser", "desktest", "desktopvpntest",
+		"devtest", "devtestuser", "envtestuser", "exectest", "extest",
+		"financetest", "ftest", "hometestvpn", "ietest", "internaltesting",
+		"itdevtest", "itstestuser", "ittest", "legaltest", "lentest",
+		"loadtest", "mailnewtest", "migtest", "mstestuser", "optionstest",
+		"printtest", "replicationtest", "safetest", "scannertest", "scantest",
+		"servicedesktest", "sftptest", "smtest", "spamtest", "sqltestuser",
+		"sqlusertest", "teste", "testvpn", "trainingtest", "typingtest",
+		"uattest", "vditestuser", "vmwaretest", "vpntest", "vtest", "wifitest",
+		"xentest",
+	},
+}
+
+// The bundled profanity list, with the language and severity of every
+// term. Short terms that occur in many innocent words are Whole.
+var profanity = []ProfanityTerm{
+	// English
+	{Term: "fuck", Severity: SeveritySevere, Lang: "en"},
+	{Term: "cunt", Severity: SeveritySevere, Lang: "en"},
+	{Term: "nigger", Severity: SeveritySevere, Lang: "en"},
+	{Term: "nigga", Severity: SeveritySevere, Lang: "en"},
+	{Term: "faggot", Severity: SeveritySevere, Lang: "en"},
+	{Term: "rape", Severity: SeveritySevere, Lang: "en"},
+	{Term: "rapist", Severity: SeveritySevere, Lang: "en"},
+	{Term: "fag", Severity: SeveritySevere, Lang: "en", Whole: true},
+	{Term: "shit", Severity: SeverityStrong, Lang: "en", Whole: true},
+	{Term: "bullshit", Severity: SeverityStrong, Lang: "en"},
+	{Term: "shithead", Severity: SeverityStrong, Lang: "en"},
+	{Term: "dipshit", Severity: SeverityStrong, Lang: "en"},
+	{Term: "bitch", Severity: SeverityStrong, Lang: "en"},
+	{Term: "bastard", Severity: SeverityStrong, Lang: "en"},
+	{Term: "dick", Severity: SeverityStrong, Lang: "en", Whole: true},
+	{Term: "dickhead", Severity: SeverityStrong, Lang: "en"},
+	{Term: "pussy", Severity: SeverityStrong, Lang: "en"},
+	{Term: "cock", Severity: SeverityStrong, Lang: "en"},
+	{Term: "whore", Severity: SeverityStrong, Lang: "en"},
+	{Term: "slut", Severity: SeverityStrong, Lang: "en"},
+	{Term: "wank", Severity: SeverityStrong, Lang: "en"},
+	{Term: "twat", Severity: SeverityStrong, Lang: "en"},
+	{Term: "asshole", Severity: SeverityStrong, Lang: "en"},
+	{Term: "dildo", Severity: SeverityStrong, Lang: "en"},
+	{Term: "porn", Severity: SeverityStrong, Lang: "en"},
+	{Term: "nazi", Severity: SeverityStrong, Lang: "en", Whole: true},
+	{Term: "retard", Severity: SeverityStrong, Lang: "en"},
+	{Term: "cum", Severity: SeverityStrong, Lang: "en", Whole: true},
+	{Term: "prick", Severity: SeverityStrong, Lang: "en", Whole: true},
+	{Term: "crap", Severity: SeverityMild, Lang: "en"},
+	{Term: "damn", Severity: SeverityMild, Lang: "en", Whole: true},
+	{Term: "piss", Severity: SeverityMild, Lang: "en"},
+	{Term: "bollocks", Severity: SeverityMild, Lang: "en"},
+	{Term: "arse", Severity: SeverityMild, Lang: "en", Whole: true},
+	{Term: "arsehole", Severity: SeverityStrong, Lang: "en"},
+	{Term: "penis", Severity: SeverityMild, Lang: "en", Whole: true},
+	{Term: "ass", Severity: SeverityMild, Lang: "en", Whole: true},
+	{Term: "tit", Severity: SeverityMild, Lang: "en", Whole: true},
+
+	// Spanish
+	{Term: "puta", Severity: SeverityStrong, Lang: "es", Whole: true},
+	{Term: "hijodeputa", Severity: SeverityStrong, Lang: "es"},
+	{Term: "mierda", Severity: SeverityStrong, Lang: "es"},
+	{Term: "cabron", Severity: SeverityStrong, Lang: "es"},
+	{Term: "pendejo", Severity: SeverityStrong, Lang: "es"},
+	{Term: "coño", Severity: SeverityStrong, Lang: "es"},
+	{Term: "joder", Severity: SeverityStrong, Lang: "es"},
+	{Term: "gilipollas", Severity: SeverityStrong, Lang: "es"},
+	{Term: "verga", Severity: SeverityStrong, Lang: "es"},
+	{Term: "marica", Severity: SeveritySevere, Lang: "es", Whole: true},
+	{Term: "culo", Severity: SeverityMild, Lang: "es", Whole: true},
+
+	// French
+	{Term: "putain", Severity: SeverityStrong, Lang: "fr"},
+	{Term: "salope", Severity: SeverityStrong, Lang: "fr"},
+	{Term: "connard", Severity: SeverityStrong, Lang: "fr"},
+	{Term: "encule", Severity: SeveritySevere, Lang: "fr"},
+	{Term: "pute", Severity: SeverityStrong, Lang: "fr"},
+	{Term: "merde", Severity: SeverityMild, Lang: "fr"},
+	{Term: "nique", Severity: SeverityStrong, Lang: "fr", Whole: true},
+
+	// German
+	{Term: "scheisse", Severity: SeverityMild, Lang: "de"},
+	{Term: "scheiße", Severity: SeverityMild, Lang: "de"},
+	{Term: "arschloch", Severity: SeverityStrong, Lang: "de"},
+	{Term: "fotze", Severity: SeveritySevere, Lang: "de"},
+	{Term: "hurensohn", Severity: SeveritySevere, Lang: "de"},
+	{Term: "wichser", Severity: SeverityStrong, Lang: "de"},
+	{Term: "fick", Severity: SeverityStrong, Lang: "de"},
+	{Term: "schlampe", Severity: SeverityStrong, Lang: "de"},
+
+	// Portuguese
+	{Term: "caralho", Severity: SeverityStrong, Lang: "pt"},
+	{Term: "porra", Severity: SeverityStrong, Lang: "pt", Whole: true},
+	{Term: "buceta", Severity: SeveritySevere, Lang: "pt"},
+	{Term: "foder", Severity: SeverityStrong, Lang: "pt"},
+	{Term: "merda", Severity: SeverityMild, Lang: "pt"},
+	{Term: "viado", Severity: SeveritySevere, Lang: "pt", Whole: true},
+
+	// Italian
+	{Term: "cazzo", Severity: SeverityStrong, Lang: "it"},
+	{Term: "vaffanculo", Severity: SeverityStrong, Lang: "it"},
+	{Term: "stronzo", Severity: SeverityStrong, Lang: "it"},
+	{Term: "puttana", Severity: SeverityStrong, Lang: "it"},
+	{Term: "minchia", Severity: SeverityStrong, Lang: "it"},
+
+	// Dutch
+	{Term: "klootzak", Severity: SeverityStrong, Lang: "nl"},
+	{Term: "godverdomme", Severity: SeverityMild, Lang: "nl"},
+}
+
+// Innocent words and place names that contain a profane term.
+var profanityExceptions = []string{
+	"scunthorpe", "cockpit", "cocktail", "peacock", "hancock", "hitchcock",
+	"woodcock", "babcock", "alcock", "shuttlecock", "cockatoo", "cockerel",
+	"cockroach", "cockney", "therapist", "therapeut", "grape", "drape",
+	"scrape", "rapeseed", "trapeze", "parapet", "compute", "dispute",
+	"repute", "ampute", "impute", "depute", "scrap", "saltwater", "swank",
+	"fickle", "retardant", "vergara",
+}
+
+// URL path segments that sites commonly serve themselves.
+var wellKnownSegments = []string{
+	".well-known", "well-known", "api", "apis", "graphql", "static",
+	"assets", "public", "media", "uploads", "cdn", "css", "js", "img",
+	"images", "fonts", "favicon.ico", "robots.txt", "sitemap.xml",
+	"humans.txt", "ads.txt", "security.txt", "manifest.json",
+	"browserconfig.xml", "crossdomain.xml", "apple-app-site-association",
+	"sw.js", "service-worker.js", "cgi-bin", "wp-admin", "wp-login.php",
+	"xmlrpc.php", "health", "healthz", "metrics", "www", "mail", "ftp",
+	"admin", "login", "logout", "signup", "oauth", "auth", "callback",
+	"settings", "help", "docs", "status",
+}
+
+// File names reserved by Windows, whatever their extension.
+var reservedFilenames = []string{
+	"con", "prn", "aux", "nul",
+	"com1", "com2", "com3", "com4", "com5", "com6", "com7", "com8", "com9",
+	"lpt1", "lpt2", "lpt3", "lpt4", "lpt5", "lpt6", "lpt7", "lpt8", "lpt9",
+}
+
+// Generic words that make a username hard to tell apart.
+var genericWords = []string{
+	"user", "username", "test", "tester", "name", "guest", "admin",
+	"account", "player", "member", "default", "login", "demo", "temp",
+	"sample", "anonymous", "nobody", "null", "none", "example", "abc",
+	"qwerty", "asdf", "xxx",
+}
@@ -0,0 +1,267 @@
+package unamex
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// Engine is an immutable set of validators and suggestors that is safe
+// for concurrent use by multiple goroutines. Unlike Identity it holds
+// no per-username state: the username is passed to every call.
+//
+// Build an Engine once at startup from a configured Identity and share it:
+//
+//	engine := New().WithValidator(myValidator).Engine()
+//
+//	// In any goroutine:
+//	if err := engine.Validate(ctx, username); err != nil {
+//		suggestions, _ := engine.Suggest(ctx, username, 5)
+//		// ...
+//	}
+type Engine struct {
+	// validator is the list of validators run by Validate.
+	// It is never modified after the Engine is built.
+	validator []Validator
+
+	// validatorCtx is the list of context-aware validators run by
+	// Validate after the validators above.
+	// It is never modified after the Engine is built.
+	validatorCtx []ValidatorCtx
+
+	// suggestor is the pool of suggestors used by Suggest.
+	// It is never modified after the Engine is built.
+	suggestor []suggestor
+
+	// rnd is the source of random choices of the suggestors;
+	// nil means the global source.
+	rnd Rand
+
+	// hashKey and hashSalt enable deterministic suggestions drawn
+	// from a HashRand seeded with the username; nil means off.
+	hashKey  []byte
+	hashSalt string
+
+	// maxAttempts is the number of candidates SuggestAvailable may
+	// generate; zero means maxSuggestAttempts.
+	maxAttempts int
+
+	// allow lets matching usernames pass failing rules; nil means none.
+	allow *Allowlist
+
+	// policy scores the suggestions of SuggestDetailed, which rank
+	// orders; nil means RankByScore.
+	policy Policy
+	rank   RankFunc
+}
+
+// Engine returns an immutable snapshot of the validators and suggestors
+// configured on the Identity. Later changes to the Identity do not
+// affect the returned Engine.
+func (u *Identity) Engine() *Engine {
+	return &Engine{
+		validator:    slices.Clone(u.validator),
+		validatorCtx: slices.Clone(u.validatorCtx),
+		suggestor:    slices.Clone(activeSuggestors(u.suggestor)),
+		rnd:          u.rnd,
+		hashKey:      u.hashKey,
+		hashSalt:     u.hashSalt,
+		maxAttempts:  u.maxAttempts,
+		allow:        u.allow,
+		policy:       u.policy,
+		rank:         u.rank,
+	}
+}
+
+// engine returns an Engine sharing the Identity's slices, for the
+// Identity's own single-goroutine use.
+func (u *Identity) engine() *Engine {
+	return &Engine{
+		validator:    u.validator,
+		validatorCtx: u.validatorCtx,
+		suggestor:    activeSuggestors(u.suggestor),
+		rnd:          u.rnd,
+		hashKey:      u.hashKey,
+		hashSalt:     u.hashSalt,
+		maxAttempts:  u.maxAttempts,
+		allow:        u.allow,
+		policy:       u.policy,
+		rank:         u.rank,
+	}
+}
+
+// run calls the validators of the Engine on name in order and passes
+// each failure to fail, stopping early when fail returns false.
+// It returns the context's error if ctx is done before all
+// validators have run.
+func (e *Engine) run(ctx context.Context, name string, fail func(error) bool) error {
+	return e.runAudit(ctx, name, fail, nil)
+}
+
+// runAudit is run that skips the failures allowed by the allowlist
+// and passes them to bypassed, if not nil. The allowlist does not
+// apply to suggestions, so that reserved names are never handed out.
+func (e *Engine) runAudit(ctx context.Context, name string, fail func(error) bool, bypassed func(Bypass)) error {
+	suggestion := isSuggestion(ctx)
+	allowed := func(err error) bool {
+		if suggestion {
+			return false
+		}
+		b, ok := e.allow.bypass(name, err)
+		if ok && bypassed != nil {
+			bypassed(b)
+		}
+		return ok
+	}
+
+	for _, f := range e.validator {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if ok, err := f(name); !ok && !allowed(err) && !fail(err) {
+			return nil
+		}
+	}
+	for _, f := range e.validatorCtx {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := f(ctx, name); err != nil && !allowed(err) && !fail(err) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Validate checks name against every validator of the Engine and
+// returns the error of the first failing one. It stops with the
+// context's error if ctx is done before all validators have run.
+func (e *Engine) Validate(ctx context.Context, name string) error {
+	var first error
+	if err := e.run(ctx, name, func(err error) bool {
+		first = err
+		return false
+	}); err != nil {
+		return err
+	}
+	return first
+}
+
+// ValidateAll checks name against every validator of the Engine and
+// returns all failures combined with errors.Join, as Identity.ValidateAll.
+func (e *Engine) ValidateAll(ctx context.Context, name string) error {
+	var errs []error
+	err := e.run(ctx, name, func(err error) bool {
+		if err == nil {
+			err = ErrInvalid
+		}
+		errs = append(errs, err)
+		return true
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+// Suggest generates up to n alternatives to name, as Identity.Suggest;
+// an empty name, or an n of zero or less, has none.
+// The suggestors are tried in a random order weighted by their weights
+// on a private copy of the pool, so concurrent calls never share
+// mutable state. If ctx is done
+// before n suggestors have been tried, the suggestions gathered so far
+// are returned with the context's error.
+func (e *Engine) Suggest(ctx context.Context, name string, n int) ([]string, error) {
+	found, err := e.suggest(ctx, name, n)
+	suggestions := make([]string, len(found))
+	for i, s := range found {
+		suggestions[i] = s.Candidate
+	}
+	return suggestions, err
+}
+
+// suggest implements Suggest, reporting the suggestor of each
+// suggestion.
+func (e *Engine) suggest(ctx context.Context, name string, n int) ([]Suggestion, error) {
+	if name == "" {
+		return nil, ctx.Err()
+	}
+	n = min(max(n, 0), len(e.suggestor))
+
+	r := e.rand(name)
+
+	pool := slices.Clone(e.suggestor)
+	shuffleSuggestorsRand(r, pool)
+
+	suggestions := make([]Suggestion, 0, n)
+
+	seen := make(map[string]bool)
+
+	for _, suggestor := range pool[:n] {
+		if err := ctx.Err(); err != nil {
+			return suggestions, err
+		}
+
+		suggestion := suggestor.fn(r, name)
+
+		if !e.isValid(ctx, name, suggestion) {
+			continue
+		}
+
+		if !seen[suggestion] {
+			suggestions = append(suggestions, Suggestion{
+				Candidate: suggestion, Suggestor: suggestor.name,
+			})
+			seen[suggestion] = true
+		}
+	}
+
+	return suggestions, ctx.Err()
+}
+
+// rand returns the source of random choices of the Engine for name:
+// a fresh HashRand in deterministic mode, the configured source or
+// the global one otherwise.
+func (e *Engine) rand(name string) Rand {
+	if e.hashKey != nil {
+		return NewHashRand(e.hashKey, name, e.hashSalt, 0)
+	}
+	if e.rnd == nil {
+		return globalRand{}
+	}
+	return e.rnd
+}
+
+// isValid reports whether suggestion passes every validator and
+// differs from name. With no validators configured, the default
+// ones apply.
+func (e *Engine) isValid(ctx context.Context, name, suggestion string) bool {
+	if name == suggestion {
+		return false
+	}
+
+	if len(e.validator) <= 0 && len(e.validatorCtx) <= 0 {
+		e = &Engine{validator: defaultValidator()}
+	}
+
+	valid := true
+	if err := e.run(forSuggestion(ctx), suggestion, func(error) bool {
+		valid = false
+		return false
+	}); err != nil {
+		return false
+	}
+	return valid
+}
+
+// suggestionKey marks the context of the validation of a suggestion.
+type suggestionKey struct{}
+
+// forSuggestion returns ctx marked as validating a suggestion, which
+// validators must reject if it names an existing user, even one they
+// would let validate its own username.
+func forSuggestion(ctx context.Context) context.Context {
+	return context.WithValue(ctx, suggestionKey{}, true)
+}
+
+// isSuggestion reports whether ctx was marked by forSuggestion.
+func isSuggestion(ctx context.Context) bool {
+	return ctx.Value(suggestionKey{}) != nil
+}
@@ -0,0 +1,253 @@
+package unamex
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Rule identifiers reported by the built-in validators in
+// ValidationError.Rule.
+const (
+	RuleRange      = "range"
+	RuleFormat     = "format"
+	RuleIntegrity  = "integrity"
+	RuleConfusable = "confusable"
+	RuleProfanity  = "profanity"
+	RuleRoute      = "route"
+	RuleSimilarity = "similarity"
+	RuleQuality    = "quality"
+)
+
+// Code classifies the reason a username failed a validation rule.
+// It is stable and intended for mapping failures to API error codes
+// or UI hints without matching on error messages.
+type Code uint8
+
+const (
+	// CodeEmpty reports an empty username.
+	CodeEmpty Code = iota + 1
+	// CodeTooShort reports a username shorter than the minimum length.
+	CodeTooShort
+	// CodeTooLong reports a username longer than the maximum length.
+	CodeTooLong
+	// CodeBadChar reports a character that is not allowed.
+	CodeBadChar
+	// CodeLeadingSep reports a username starting with a separator.
+	CodeLeadingSep
+	// CodeTrailingSep reports a username ending with a separator.
+	CodeTrailingSep
+	// CodeTooManySeps reports more separators than allowed.
+	CodeTooManySeps
+	// CodeDigitsOnly reports a username made up of digits only.
+	CodeDigitsOnly
+	// CodeBlacklisted reports a weak, common or reserved username.
+	CodeBlacklisted
+	// CodeConsecutiveSeps reports two separators in a row.
+	CodeConsecutiveSeps
+	// CodeScriptNotAllowed reports a letter of a script the policy
+	// does not allow.
+	CodeScriptNotAllowed
+	// CodeMixedScripts reports letters of more than one script.
+	CodeMixedScripts
+	// CodeConfusable reports a username that looks like a blacklisted,
+	// reserved or protected one.
+	CodeConfusable
+	// CodeProfane reports a username containing an offensive term.
+	CodeProfane
+	// CodeRouteCollision reports a username that collides with a web
+	// route or a reserved file name.
+	CodeRouteCollision
+	// CodeTooSimilar reports a username within the edit distance of a
+	// protected username.
+	CodeTooSimilar
+	// CodeLowScore reports a username whose Score is below the minimum.
+	CodeLowScore
+)
+
+// Sentinel errors matching each Code. A *ValidationError unwraps to
+// the sentinel of its Code, so callers can use errors.Is:
+//
+//	if errors.Is(err, unamex.ErrTooShort) {
+//		// ...
+//	}
+var (
+	ErrEmpty       = errors.New("username cannot be empty")
+	ErrTooShort    = errors.New("username is too short")
+	ErrTooLong     = errors.New("username is too long")
+	ErrBadChar     = errors.New("username contains a character that is not allowed")
+	ErrLeadingSep  = errors.New("username cannot start with a separator")
+	ErrTrailingSep = errors.New("username cannot end with a separator")
+	ErrTooManySeps = errors.New("username contains too many separators")
+	ErrDigitsOnly  = errors.New("username cannot contain only digits")
+	ErrBlacklisted = errors.New("username is too weak or common")
+
+	ErrConsecutiveSeps  = errors.New("username cannot contain consecutive separators")
+	ErrScriptNotAllowed = errors.New("username contains letters of a script that is not allowed")
+	ErrMixedScripts     = errors.New("username cannot mix letters of different scripts")
+	ErrConfusable       = errors.New("username looks like a protected name")
+	ErrProfane          = errors.New("username contains offensive language")
+	ErrRouteCollision   = errors.New("username collides with a route or file name")
+	ErrTooSimilar       = errors.New("username is too similar to a protected name")
+	ErrLowScore         = errors.New("username is too easy to guess")
+)
+
+// ErrInvalid is reported by ValidateAll for a validator that fails
+// without returning an error.
+var ErrInvalid = errors.New("username is invalid")
+
+// codeNames and codeErrors are indexed by Code.
+var codeNames = [...]string{
+	CodeEmpty:       "Empty",
+	CodeTooShort:    "TooShort",
+	CodeTooLong:     "TooLong",
+	CodeBadChar:     "BadChar",
+	CodeLeadingSep:  "LeadingSep",
+	CodeTrailingSep: "TrailingSep",
+	CodeTooManySeps: "TooManySeps",
+	CodeDigitsOnly:  "DigitsOnly",
+	CodeBlacklisted: "Blacklisted",
+
+	CodeConsecutiveSeps:  "ConsecutiveSeps",
+	CodeScriptNotAllowed: "ScriptNotAllowed",
+	CodeMixedScripts:     "MixedScripts",
+	CodeConfusable:       "Confusable",
+	CodeProfane:          "Profane",
+	CodeRouteCollision:   "RouteCollision",
+	CodeTooSimilar:       "TooSimilar",
+	CodeLowScore:         "LowScore",
+}
+
+var codeErrors = [...]error{
+	CodeEmpty:       ErrEmpty,
+	CodeTooShort:    ErrTooShort,
+	CodeTooLong:     ErrTooLong,
+	CodeBadChar:     ErrBadChar,
+	CodeLeadingSep:  ErrLeadingSep,
+	CodeTrailingSep: ErrTrailingSep,
+	CodeTooManySeps: ErrTooManySeps,
+	CodeDigitsOnly:  ErrDigitsOnly,
+	CodeBlacklisted: ErrBlacklisted,
+
+	CodeConsecutiveSeps:  ErrConsecutiveSeps,
+	CodeScriptNotAllowed: ErrScriptNotAllowed,
+	CodeMixedScripts:     ErrMixedScripts,
+	CodeConfusable:       ErrConfusable,
+	CodeProfane:          ErrProfane,
+	CodeRouteCollision:   ErrRouteCollision,
+	CodeTooSimilar:       ErrTooSimilar,
+	CodeLowScore:         ErrLowScore,
+}
+
+// String returns the name of the code, such as "TooShort".
+func (c Code) String() string {
+	if int(c) < len(codeNames) && codeNames[c] != "" {
+		return codeNames[c]
+	}
+	return fmt.Sprintf("Code(%d)", uint8(c))
+}
+
+// ValidationError describes a single failed validation rule.
+// It is returned by Identity.Validate and by every built-in validator.
+//
+// Example usage:
+//
+//	var ve *unamex.ValidationError
+//	if errors.As(err, &ve) {
+//		fmt.Println(ve.Rule, ve.Code, ve.Pos, ve.Min, ve.Max)
+//	}
+type ValidationError struct {
+	// Rule is the identifier of the rule that failed, such as RuleRange.
+	Rule string
+
+	// Code classifies the failure.
+	Code Code
+
+	// Pos is the byte offset of the offending character,
+	// or -1 when the failure is not tied to a single position.
+	Pos int
+
+	// Char is the offending character, or 0 when Pos is -1.
+	Char rune
+
+	// Min and Max are the limits involved in the failure, if any:
+	// the length bounds for CodeTooShort and CodeTooLong, the
+	// separator limit for CodeTooManySeps and the least score for
+	// CodeLowScore.
+	Min, Max int
+
+	// Script is the Unicode script of Char, such as "Cyrillic",
+	// for CodeScriptNotAllowed and CodeMixedScripts.
+	Script string
+
+	// Normalized is the form of the username that was looked up,
+	// after the policy's Normalization, for CodeBlacklisted.
+	Normalized string
+
+	// Match is the name the username was matched against: the
+	// blacklisted or reserved word for CodeBlacklisted, the name it
+	// looks like for CodeConfusable, the offensive term for CodeProfane,
+	// the route pattern or file name for CodeRouteCollision, the
+	// nearest protected name for CodeTooSimilar.
+	Match string
+
+	// Distance is the edit distance to Match for CodeTooSimilar.
+	Distance int
+
+	// Score is the score of the username for CodeLowScore.
+	Score int
+
+	// Category is the category of the entry matched for
+	// CodeBlacklisted, such as CategorySystem, or "" for an entry of
+	// Policy.Blacklist or Policy.Reserved.
+	Category string
+
+	// Severity ranks the offensive term for CodeProfane.
+	Severity Severity
+}
+
+// Error returns a human readable description of the failure.
+func (e *ValidationError) Error() string {
+	switch e.Code {
+	case CodeTooShort, CodeTooLong:
+		return fmt.Sprintf("username must be between %d and %d characters",
+			e.Min, e.Max)
+	case CodeBadChar:
+		return fmt.Sprintf("username contains %q at position %d, "+
+			"usernames can only contain letters, numbers, and separators",
+			e.Char, e.Pos)
+	case CodeTooManySeps:
+		return fmt.Sprintf("username can contain at most %d separator(s)", e.Max)
+	case CodeScriptNotAllowed:
+		return fmt.Sprintf("username contains %q at position %d, "+
+			"letters of the %s script are not allowed", e.Char, e.Pos, e.Script)
+	case CodeMixedScripts:
+		return fmt.Sprintf("username contains %q at position %d, "+
+			"usernames cannot mix %s letters with letters of another script",
+			e.Char, e.Pos, e.Script)
+	case CodeConfusable:
+		return fmt.Sprintf("username looks like %q, please choose a different one", e.Match)
+	case CodeProfane:
+		return "username contains offensive language, please choose a different one"
+	case CodeLowScore:
+		return fmt.Sprintf("username scores %d out of 100, at least %d is required", e.Score, e.Min)
+	case CodeTooSimilar:
+		return fmt.Sprintf("username is too similar to %q, please choose a different one", e.Match)
+	case CodeRouteCollision:
+		return fmt.Sprintf("username %q is reserved by the site, please choose a different one", e.Normalized)
+	case CodeBlacklisted:
+		return "username is too weak or common, please choose a different one"
+	}
+	if err := e.Unwrap(); err != nil {
+		return err.Error()
+	}
+	return ErrInvalid.Error()
+}
+
+// Unwrap returns the sentinel error matching the Code,
+// which makes errors.Is work against ErrTooShort and friends.
+func (e *ValidationError) Unwrap() error {
+	if int(e.Code) < len(codeErrors) {
+		return codeErrors[e.Code]
+	}
+	return nil
+}
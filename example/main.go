@@ -1,6 +1,7 @@
 package example
 
 import (
+	"context"
 	"errors"
 
 	"github.com/remoree/unamex"
@@ -15,26 +16,29 @@ var out = fmtPrinter.Println
 
 // Check validates a username and generates suggestions if invalid or unavailable.
 // Input:
+//   - ctx: Bounds the availability lookups.
 //   - username: The username to validate.
 //
 // Returns:
 //   - A slice of suggested usernames if validation fails.
 //   - An error if validation fails or the username is unavailable.
-func Check(username string) (suggestions []string, err error) {
+func Check(ctx context.Context, username string) (suggestions []string, err error) {
 	// Ensure the username is not empty
 	if username == "" {
 		return nil, errors.New("username cannot be empty")
 	}
 
-	// Create a new Identity instance with the given username
-	u := unamex.New(username)
+	// Create a new Identity instance with the given username and
+	// keep the availability rule so suggestions are checked too
+	u := unamex.New(username).AddValidatorCtx(withAvailabilityCheck)
 
 	// Validate the username with custom availability rules
-	if err := u.Validate(withAvailabilityCheck); err != nil {
+	if err := u.ValidateContext(ctx); err != nil {
 		// If the error is due to availability, generate suggestions
 		if errors.Is(err, errAvailability) {
 			capacity := 5 // Number of suggestions to generate
-			return u.Suggest(capacity), err
+			suggestions, _ := u.SuggestContext(ctx, capacity)
+			return suggestions, err
 		}
 
 		// Return any other validation error
@@ -47,21 +51,28 @@ func Check(username string) (suggestions []string, err error) {
 }
 
 // withAvailabilityCheck is a custom validation rule that checks if
-// a username is available.
+// a username is available. A real implementation would query a
+// database and honor ctx.
 // Input:
+//   - ctx: Carries the deadline of the lookup.
 //   - s: The username to check.
 //
 // Returns:
-//   - false and errAvailability if the username is unavailable.
-//   - true if the username is available.
-func withAvailabilityCheck(s string) (bool, error) {
+//   - errAvailability if the username is unavailable.
+//   - The context's error if ctx is done.
+//   - nil if the username is available.
+func withAvailabilityCheck(ctx context.Context, s string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Mark specific usernames as unavailable
 	if s == "moree" { // Example reserved username
-		return false, errAvailability
+		return errAvailability
 	}
 
 	// Indicate that the username is available
-	return true, nil
+	return nil
 }
 
 // errAvailability is a custom error returned when a username is unavailable.
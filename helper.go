@@ -1,8 +1,6 @@
 package unamex
 
-import (
-	"math/rand"
-)
+import "unicode/utf8"
 
 const (
 	// vowelsBitset         = 1065233
@@ -30,6 +28,11 @@ func DoubleByte(b byte) []byte {
 // If there are no vowels in the string or if the only vowel
 // is the first character, it returns the original string.
 func VanishVowel(s string) string {
+	return VanishVowelRand(globalRand{}, s)
+}
+
+// VanishVowelRand is like VanishVowel but draws its random choices from r.
+func VanishVowelRand(r Rand, s string) string {
 	b := []byte(s)
 	var lastVowelIndex int = -1
 
@@ -39,7 +42,7 @@ func VanishVowel(s string) string {
 		if isVowel(c) && idx != 0 {
 			lastVowelIndex = idx
 
-			if rand.Intn(2) == 1 {
+			if r.IntN(2) == 1 {
 				b = append(b[:idx], b[idx+1:]...)
 				return string(b)
 			}
@@ -70,6 +73,12 @@ func VanishVowel(s string) string {
 //		result := VowelTransform("hello", changeVowel)
 //		fmt.Println("Transformed string:", result) // Output: hallo
 func VowelTransform(s string, f func(byte) byte) string {
+	return VowelTransformRand(globalRand{}, s, f)
+}
+
+// VowelTransformRand is like VowelTransform but draws its random
+// choices from r.
+func VowelTransformRand(r Rand, s string, f func(byte) byte) string {
 	b := []byte(s)
 	var lastVowelIndex int = -1
 	var char byte
@@ -79,14 +88,14 @@ func VowelTransform(s string, f func(byte) byte) string {
 			lastVowelIndex = i
 			char = b[i]
 
-			if rand.Intn(2) == 1 {
+			if r.IntN(2) == 1 {
 				b[i] = f(char)
 				return string(b)
 			}
 		}
 	}
 
-	if s == string(b) {
+	if s == string(b) && lastVowelIndex >= 0 {
 		b[lastVowelIndex] = f(char)
 	}
 	return string(b)
@@ -98,12 +107,17 @@ func VowelTransform(s string, f func(byte) byte) string {
 // it duplicates the last vowel in the string.
 // If there are no vowels in the string, it returns the original string.
 func RepeatVowel(s string) string {
+	return RepeatVowelRand(globalRand{}, s)
+}
+
+// RepeatVowelRand is like RepeatVowel but draws its random choices from r.
+func RepeatVowelRand(r Rand, s string) string {
 	b := []byte(s)
 	var lastVowelIndex int = -1
 	for idx, c := range b {
 		if isVowel(c) {
 			lastVowelIndex = idx
-			if rand.Intn(2) == 1 {
+			if r.IntN(2) == 1 {
 				b = append(b[:idx+1], b[idx:]...)
 				return string(b)
 			}
@@ -142,6 +156,14 @@ func AlphabetTransform(s string, f func(byte) byte) string {
 // It then repeats the second half of the string and appends it to the first half.
 // The result is a string where the second half is repeated once.
 func RepeatSubfix(s string) string {
+	if s == "" {
+		return s
+	}
+	if !isASCII(s) {
+		r := []rune(s)
+		m := len(r) / 2
+		return string(append(r[:m+1:m+1], r[m:]...))
+	}
 	var b = []byte(s)
 	var m = len(b) / 2
 	b = append(b[:m+1], b[m:]...)
@@ -151,6 +173,13 @@ func RepeatSubfix(s string) string {
 // RepeatPrefix repeats the first character of the string
 // and appends it to the beginning of the string.
 func RepeatPrefix(s string) string {
+	if s == "" {
+		return s
+	}
+	if !isASCII(s) {
+		_, n := utf8.DecodeRuneInString(s)
+		return s[:n] + s
+	}
 	var b = []byte(s)
 	b = append(b[:1], b...)
 	return string(b)
@@ -159,6 +188,13 @@ func RepeatPrefix(s string) string {
 // RepeatSuffix repeats the last character of the string
 // and appends it to the end of the string.
 func RepeatSuffix(s string) string {
+	if s == "" {
+		return s
+	}
+	if !isASCII(s) {
+		_, n := utf8.DecodeLastRuneInString(s)
+		return s + s[len(s)-n:]
+	}
 	var b = []byte(s)
 	var suffix = b[len(b)-1:]
 	b = append(b, suffix...)
@@ -168,6 +204,13 @@ func RepeatSuffix(s string) string {
 // SetPostInitialSep inserts the byte separator right after
 // the first character of the input string.
 func SetPostInitialSep(s string, sep byte) string {
+	if s == "" {
+		return s
+	}
+	if !isASCII(s) {
+		_, n := utf8.DecodeRuneInString(s)
+		return s[:n] + string(sep) + s[n:]
+	}
 	var b = []byte(s)
 	b = append(b[:1], sep)
 	b = append(b, []byte(s[1:])...)
@@ -177,6 +220,13 @@ func SetPostInitialSep(s string, sep byte) string {
 // SetPenultimateSep inserts the byte right before the last
 // character of the input string.
 func SetPenultimateSep(s string, sep byte) string {
+	if s == "" {
+		return s
+	}
+	if !isASCII(s) {
+		_, n := utf8.DecodeLastRuneInString(s)
+		return s[:len(s)-n] + string(sep) + s[len(s)-n:]
+	}
 	var b = []byte(s)
 	var last = b[len(b)-1]
 	b = append(append(b[:len(b)-1], sep), last)
@@ -186,16 +236,28 @@ func SetPenultimateSep(s string, sep byte) string {
 // SetPenultimateSepDigit appends the byte and a random digit
 // to the end of the input string.
 func SetPenultimateSepDigit(s string, sep byte) string {
+	return SetPenultimateSepDigitRand(globalRand{}, s, sep)
+}
+
+// SetPenultimateSepDigitRand is like SetPenultimateSepDigit but draws its random
+// choices from r.
+func SetPenultimateSepDigitRand(r Rand, s string, sep byte) string {
 	var b = []byte(s)
-	b = append(b, sep, byte(rand.Intn(10)+'0'))
+	b = append(b, sep, byte(r.IntN(10)+'0'))
 	return string(b)
 }
 
 // SetPostInitialSepDigit inserts a random digit and the byte right
 // after the first character of the input string.
 func SetPostInitialSepDigit(s string, sep byte) string {
+	return SetPostInitialSepDigitRand(globalRand{}, s, sep)
+}
+
+// SetPostInitialSepDigitRand is like SetPostInitialSepDigit but draws its random
+// choices from r.
+func SetPostInitialSepDigitRand(r Rand, s string, sep byte) string {
 	var b = []byte(s)
-	digit := byte(rand.Intn(10) + '0')
+	digit := byte(r.IntN(10) + '0')
 	b = append(b, digit, sep)
 	b = append(b[len(b)-2:], b[:len(b)-2]...)
 	return string(b)
@@ -204,9 +266,15 @@ func SetPostInitialSepDigit(s string, sep byte) string {
 // SepWithRandomDigit appends the byte and a random digit from the range 0
 // to nRange to the end of the input string.
 func SepWithRandomDigit(s string, sep byte, nRange int) string {
+	return SepWithRandomDigitRand(globalRand{}, s, sep, nRange)
+}
+
+// SepWithRandomDigitRand is like SepWithRandomDigit but draws its random
+// choices from r.
+func SepWithRandomDigitRand(r Rand, s string, sep byte, nRange int) string {
 	var b = []byte(s)
 	b = append(b, sep)
-	b = append(b, byteNumbers[rand.Intn(nRange)]...)
+	b = append(b, byteNumbers[r.IntN(nRange)]...)
 	return string(b)
 }
 
@@ -216,11 +284,22 @@ func SepWithRandomDigit(s string, sep byte, nRange int) string {
 // it swaps the two middle characters. Otherwise, it swaps characters
 // at one-third and two-thirds of the way through the string.
 func SwapTwoChars(s string) string {
-	var b = []byte(s)
-	if len(b) < 3 {
+	if len(s) < 3 {
 		return s
 	}
+	if !isASCII(s) {
+		r := []rune(s)
+		if len(r) < 3 {
+			return s
+		}
+		return string(swapTwo(r))
+	}
+	return string(swapTwo([]byte(s)))
+}
 
+// swapTwo implements SwapTwoChars on bytes or runes; b has at least
+// three elements and is swapped in place.
+func swapTwo[T byte | rune](b []T) []T {
 	switch {
 	// If the the last two characters are not the same
 	case b[len(b)-1] != b[len(b)-2]:
@@ -239,19 +318,26 @@ func SwapTwoChars(s string) string {
 		b[oneThird], b[twoThirds] = b[twoThirds], b[oneThird]
 	}
 
-	return string(b)
+	return b
 }
 
 // RepeatInitialAppendDigit repeats the first character of the string
 // and appends it to the beginning of the string.
 // It then appends a random digit from the range 0 to nRange to the end of the string.
 func RepeatInitialAppendDigit(s string, nRange int) string {
+	return RepeatInitialAppendDigitRand(globalRand{}, s, nRange)
+}
+
+// RepeatInitialAppendDigitRand is like RepeatInitialAppendDigit but draws its random
+// choices from r.
+func RepeatInitialAppendDigitRand(r Rand, s string, nRange int) string {
 	var b = []byte(s)
-	b = append(b[:1], b...)
-	// If this scheme is needed -> byte(rand.Intn(10)+'0')
+	_, n := utf8.DecodeRuneInString(s)
+	b = append(b[:n:n], b...)
+	// If this scheme is needed -> byte(r.IntN(10)+'0')
 	// Add ‘0’ (which is 48 in ASCII) to the random number
 	// to get the correct ASCII value of the digit
-	b = append(b, byteNumbers[rand.Intn(nRange)]...)
+	b = append(b, byteNumbers[r.IntN(nRange)]...)
 	return string(b)
 }
 
@@ -260,6 +346,12 @@ func RepeatInitialAppendDigit(s string, nRange int) string {
 // Finally, it moves the appended digit to the beginning of the string.
 // The place of the digit in the string depends on the value of nRange.
 func PrefixRandomDigit(s string, nRange int) string {
+	return PrefixRandomDigitRand(globalRand{}, s, nRange)
+}
+
+// PrefixRandomDigitRand is like PrefixRandomDigit but draws its random
+// choices from r.
+func PrefixRandomDigitRand(r Rand, s string, nRange int) string {
 	var b = []byte(s)
 	var place int = 1
 	var lowerBound = 0
@@ -271,7 +363,7 @@ func PrefixRandomDigit(s string, nRange int) string {
 		lowerBound = 100
 	}
 
-	digit := byteNumbers[rand.Intn(nRange-lowerBound)+lowerBound]
+	digit := byteNumbers[r.IntN(nRange-lowerBound)+lowerBound]
 	b = append(b, digit...)
 	b = append(b[len(b)-place:], b[:len(b)-place]...)
 	return string(b)
@@ -280,8 +372,14 @@ func PrefixRandomDigit(s string, nRange int) string {
 // SuffixRandomDigit generates a random digit from the range specified by nRange.
 // It then appends this digit to the end of the string.
 func SuffixRandomDigit(s string, nRange int) string {
+	return SuffixRandomDigitRand(globalRand{}, s, nRange)
+}
+
+// SuffixRandomDigitRand is like SuffixRandomDigit but draws its random
+// choices from r.
+func SuffixRandomDigitRand(r Rand, s string, nRange int) string {
 	var b = []byte(s)
-	b = append(b, byteNumbers[rand.Intn(nRange)]...)
+	b = append(b, byteNumbers[r.IntN(nRange)]...)
 	return string(b)
 }
 
@@ -289,13 +387,19 @@ func SuffixRandomDigit(s string, nRange int) string {
 // Depending on the generated number, it calls the PrefixRandomDigit
 // function with different range parameters.
 func SetPrefixRandomDigit(s string) string {
-	switch rand.Intn(3) {
+	return SetPrefixRandomDigitRand(globalRand{}, s)
+}
+
+// SetPrefixRandomDigitRand is like SetPrefixRandomDigit but draws its random
+// choices from r.
+func SetPrefixRandomDigitRand(r Rand, s string) string {
+	switch r.IntN(3) {
 	case 0:
-		return PrefixRandomDigit(s, 1000)
+		return PrefixRandomDigitRand(r, s, 1000)
 	case 1:
-		return PrefixRandomDigit(s, 100)
+		return PrefixRandomDigitRand(r, s, 100)
 	default:
-		return PrefixRandomDigit(s, 10)
+		return PrefixRandomDigitRand(r, s, 10)
 	}
 }
 
@@ -303,13 +407,19 @@ func SetPrefixRandomDigit(s string) string {
 // Depending on the generated number, it calls the SuffixRandomDigit
 // function with different range parameters.
 func SetSuffixRandomDigit(s string) string {
-	switch rand.Intn(3) {
+	return SetSuffixRandomDigitRand(globalRand{}, s)
+}
+
+// SetSuffixRandomDigitRand is like SetSuffixRandomDigit but draws its random
+// choices from r.
+func SetSuffixRandomDigitRand(r Rand, s string) string {
+	switch r.IntN(3) {
 	case 0:
-		return SuffixRandomDigit(s, 1000)
+		return SuffixRandomDigitRand(r, s, 1000)
 	case 1:
-		return SuffixRandomDigit(s, 100)
+		return SuffixRandomDigitRand(r, s, 100)
 	default:
-		return SuffixRandomDigit(s, 10)
+		return SuffixRandomDigitRand(r, s, 10)
 	}
 }
 
@@ -317,20 +427,57 @@ func SetSuffixRandomDigit(s string) string {
 // Depending on the generated number, it calls the SepWithRandomDigit
 // function with different range parameters.
 func SetSepWithRandomDigit(s string) string {
-	switch rand.Intn(3) {
+	return SetSepWithRandomDigitRand(globalRand{}, s)
+}
+
+// SetSepWithRandomDigitRand is like SetSepWithRandomDigit but draws
+// its random choices from r.
+func SetSepWithRandomDigitRand(r Rand, s string) string {
+	return setSepWithRandomDigit(r, s, separator)
+}
+
+// setSepWithRandomDigit is SetSepWithRandomDigitRand with a custom separator.
+func setSepWithRandomDigit(r Rand, s string, sep byte) string {
+	switch r.IntN(3) {
 	case 0:
-		return SepWithRandomDigit(s, separator, 1000)
+		return SepWithRandomDigitRand(r, s, sep, 1000)
 	case 1:
-		return SepWithRandomDigit(s, separator, 100)
+		return SepWithRandomDigitRand(r, s, sep, 100)
 	default:
-		return SepWithRandomDigit(s, separator, 10)
+		return SepWithRandomDigitRand(r, s, sep, 10)
 	}
 }
 
-func shuffleSuggestors(slice []Suggestor) {
-	rand.Shuffle(len(slice), func(i, j int) {
+// shuffleSuggestorsRand shuffles slice in place drawing from r, each
+// position taking one of the remaining suggestors with a probability
+// proportional to its weight, which must be positive. Equal weights
+// get a Fisher-Yates shuffle.
+func shuffleSuggestorsRand(r Rand, slice []suggestor) {
+	total := 0
+	uniform := true
+	for _, s := range slice {
+		total += s.weight
+		uniform = uniform && s.weight == slice[0].weight
+	}
+
+	if uniform {
+		for i := len(slice) - 1; i > 0; i-- {
+			j := r.IntN(i + 1)
+			slice[i], slice[j] = slice[j], slice[i]
+		}
+		return
+	}
+
+	for i := 0; i < len(slice)-1; i++ {
+		x := r.IntN(total)
+		j := i
+		for x >= slice[j].weight {
+			x -= slice[j].weight
+			j++
+		}
+		total -= slice[j].weight
 		slice[i], slice[j] = slice[j], slice[i]
-	})
+	}
 }
 func isLetter(c byte) bool {
 	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
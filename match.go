@@ -0,0 +1,358 @@
+package unamex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MatchMode selects how a blacklist entry is compared with a username.
+type MatchMode uint8
+
+const (
+	// MatchExact matches the whole username. It is the mode of an
+	// entry without a mode prefix.
+	MatchExact MatchMode = iota
+	// MatchPrefix matches usernames starting with the entry,
+	// written "prefix:admin".
+	MatchPrefix
+	// MatchSuffix matches usernames ending with the entry,
+	// written "suffix:admin".
+	MatchSuffix
+	// MatchContains matches usernames containing the entry anywhere,
+	// written "contains:admin".
+	MatchContains
+	// MatchGlob matches usernames against a shell pattern where *
+	// matches any run of characters and ? any single one,
+	// written "glob:adm?n*".
+	MatchGlob
+	// MatchRegex matches usernames containing a match of a regular
+	// expression in the syntax of the regexp package,
+	// written "regex:^adm[1i]n".
+	MatchRegex
+)
+
+// matchModes are the prefixes of the entries, indexed by MatchMode.
+var matchModes = [...]string{
+	MatchExact:    "exact",
+	MatchPrefix:   "prefix",
+	MatchSuffix:   "suffix",
+	MatchContains: "contains",
+	MatchGlob:     "glob",
+	MatchRegex:    "regex",
+}
+
+// String returns the prefix of the mode, such as "prefix".
+func (m MatchMode) String() string {
+	if int(m) < len(matchModes) {
+		return matchModes[m]
+	}
+	return fmt.Sprintf("MatchMode(%d)", uint8(m))
+}
+
+// parseEntry splits a blacklist entry into its mode and text.
+// An entry without a known mode prefix is matched exactly.
+func parseEntry(entry string) (MatchMode, string) {
+	if i := strings.IndexByte(entry, ':'); i > 0 {
+		for m, name := range matchModes {
+			if entry[:i] == name {
+				return MatchMode(m), entry[i+1:]
+			}
+		}
+	}
+	return MatchExact, entry
+}
+
+// checkEntry reports whether a blacklist entry compiles. An entry
+// with no text is rejected whatever its mode, as an empty glob or
+// regex would match every username.
+func checkEntry(entry string) error {
+	mode, text := parseEntry(entry)
+	if text == "" {
+		return fmt.Errorf("blacklist entry %q is empty", entry)
+	}
+	var err error
+	switch mode {
+	case MatchGlob:
+		_, err = regexp.Compile(globToRegexp(text))
+	case MatchRegex:
+		_, err = regexp.Compile(text)
+	}
+	if err != nil {
+		return fmt.Errorf("blacklist entry %q: %w", entry, err)
+	}
+	return nil
+}
+
+// Matcher checks a username against every blacklisted and reserved
+// entry of a policy in a single pass: the exact, prefix, suffix and
+// contains entries are compiled into an Aho–Corasick automaton, and
+// the glob and regex entries into a single regular expression.
+// A Matcher is immutable and safe for concurrent use.
+type Matcher struct {
+	policy Policy
+
+	// literals holds the automaton of the exact, prefix, suffix and
+	// contains entries, whose mode and original form are in lits.
+	literals *ahoCorasick
+	lits     []literal
+
+	// pattern is the alternation of patterns, or nil when there is
+	// none; patterns tells which one matched.
+	pattern  *regexp.Regexp
+	patterns []entryPattern
+}
+
+type literal struct {
+	mode     MatchMode
+	entry    string
+	category string
+}
+
+type entryPattern struct {
+	re       *regexp.Regexp
+	entry    string
+	category string
+}
+
+// Matcher compiles the blacklisted and reserved entries of the policy,
+// along with the entries of its enabled categories.
+// Entries are written "mode:text", as described for MatchMode; the
+// text of exact, prefix, suffix and contains entries goes through the
+// policy's Normalization, glob and regex patterns are matched against
+// the normalized username as written, ignoring case unless the
+// Normalization leaves it out.
+//
+// Example usage:
+//
+//	p := unamex.DefaultPolicy()
+//	p.Reserved = []string{"contains:admin", "prefix:acme", "glob:test*user"}
+//	m, err := p.Matcher()
+//	if err != nil {
+//		log.Fatal(err) // invalid glob or regex
+//	}
+//	entry, ok := m.Match("xxadminxx") // "contains:admin", true
+func (p Policy) Matcher() (*Matcher, error) {
+	return p.compile(p.reservedWords())
+}
+
+// compile builds the Matcher of words under the policy.
+func (p Policy) compile(words []reservedWord) (*Matcher, error) {
+	m := &Matcher{policy: p}
+	fold := p.Normalization == 0 || p.Normalization&FoldCase != 0
+
+	var keywords []string
+	var alternation []string
+	for _, w := range words {
+		entry := w.entry
+		mode, text := parseEntry(entry)
+		var expr string
+		switch mode {
+		case MatchGlob:
+			expr = globToRegexp(text)
+		case MatchRegex:
+			expr = text
+		default:
+			text = p.Normalize(text)
+			if text == "" {
+				return nil, fmt.Errorf("unamex: blacklist entry %q is empty", entry)
+			}
+			keywords = append(keywords, text)
+			m.lits = append(m.lits, literal{mode: mode, entry: entry, category: w.category})
+			continue
+		}
+
+		if text == "" {
+			return nil, fmt.Errorf("unamex: blacklist entry %q is empty", entry)
+		}
+		if fold {
+			expr = "(?i:" + expr + ")"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("unamex: blacklist entry %q: %w", entry, err)
+		}
+		m.patterns = append(m.patterns, entryPattern{re: re, entry: entry, category: w.category})
+		alternation = append(alternation, "(?:"+expr+")")
+	}
+
+	m.literals = newAhoCorasick(keywords)
+	if len(alternation) > 0 {
+		m.pattern = regexp.MustCompile(strings.Join(alternation, "|"))
+	}
+	return m, nil
+}
+
+// globToRegexp translates a glob into an anchored regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for _, part := range strings.SplitAfter(glob, "") {
+		switch part {
+		case "*":
+			b.WriteString(".*")
+		case "?":
+			b.WriteByte('.')
+		default:
+			b.WriteString(regexp.QuoteMeta(part))
+		}
+	}
+	b.WriteByte('$')
+	return b.String()
+}
+
+// Match reports whether the username matches an entry of the
+// Matcher, and returns the first entry it matches.
+func (m *Matcher) Match(username string) (entry string, ok bool) {
+	_, entry, _, ok = m.match(username)
+	return entry, ok
+}
+
+// MatchCategory is Match that also returns the category of the entry,
+// or "" for an entry without a category.
+func (m *Matcher) MatchCategory(username string) (entry, category string, ok bool) {
+	_, entry, category, ok = m.match(username)
+	return entry, category, ok
+}
+
+// match is MatchCategory that also returns the normalized username.
+func (m *Matcher) match(username string) (normalized, entry, category string, ok bool) {
+	s := m.policy.Normalize(username)
+	m.literals.scan(s, func(k, start, end int) bool {
+		l := m.lits[k]
+		switch l.mode {
+		case MatchExact:
+			ok = start == 0 && end == len(s)
+		case MatchPrefix:
+			ok = start == 0
+		case MatchSuffix:
+			ok = end == len(s)
+		case MatchContains:
+			ok = true
+		}
+		if ok {
+			entry, category = l.entry, l.category
+		}
+		return !ok
+	})
+	if ok {
+		return s, entry, category, true
+	}
+
+	if m.pattern != nil && m.pattern.MatchString(s) {
+		for _, p := range m.patterns {
+			if p.re.MatchString(s) {
+				return s, p.entry, p.category, true
+			}
+		}
+	}
+	return s, "", "", false
+}
+
+// validate is the integrity Validator of the Matcher.
+func (m *Matcher) validate(s string) (bool, error) {
+	if normalized, entry, category, ok := m.match(s); ok {
+		return false, &ValidationError{
+			Rule: RuleIntegrity, Code: CodeBlacklisted, Pos: -1,
+			Normalized: normalized, Match: entry, Category: category,
+		}
+	}
+	return true, nil
+}
+
+// ahoCorasick is an Aho–Corasick automaton finding every occurrence
+// of a set of keywords in a single pass over the text.
+type ahoCorasick struct {
+	nodes []acNode
+}
+
+type acNode struct {
+	next map[byte]int32
+	// fail is the node of the longest proper suffix of this node
+	// that is also in the trie.
+	fail int32
+	// out lists the keywords ending at this node; dict is the
+	// nearest node on the fail chain with keywords, or -1.
+	out   []int32
+	dict  int32
+	depth int32
+}
+
+// newAhoCorasick builds the automaton of keywords, which are reported
+// by their index.
+func newAhoCorasick(keywords []string) *ahoCorasick {
+	a := &ahoCorasick{nodes: []acNode{{dict: -1}}}
+	for k, w := range keywords {
+		n := int32(0)
+		for i := 0; i < len(w); i++ {
+			next, ok := a.nodes[n].next[w[i]]
+			if !ok {
+				next = int32(len(a.nodes))
+				a.nodes = append(a.nodes, acNode{dict: -1, depth: int32(i + 1)})
+				if a.nodes[n].next == nil {
+					a.nodes[n].next = make(map[byte]int32)
+				}
+				a.nodes[n].next[w[i]] = next
+			}
+			n = next
+		}
+		a.nodes[n].out = append(a.nodes[n].out, int32(k))
+	}
+
+	// Breadth-first, so the fail node of a node is complete before
+	// the node's children are visited.
+	queue := []int32{0}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for c, child := range a.nodes[n].next {
+			queue = append(queue, child)
+			if n == 0 {
+				continue
+			}
+			f := a.nodes[n].fail
+			for {
+				if next, ok := a.nodes[f].next[c]; ok {
+					a.nodes[child].fail = next
+					break
+				}
+				if f == 0 {
+					break
+				}
+				f = a.nodes[f].fail
+			}
+			fail := a.nodes[child].fail
+			if len(a.nodes[fail].out) > 0 {
+				a.nodes[child].dict = fail
+			} else {
+				a.nodes[child].dict = a.nodes[fail].dict
+			}
+		}
+	}
+	return a
+}
+
+// scan calls found for every keyword occurring in s, with the keyword
+// index and the bounds of the occurrence, until found returns false.
+func (a *ahoCorasick) scan(s string, found func(k, start, end int) bool) {
+	n := int32(0)
+	for i := 0; i < len(s); i++ {
+		for {
+			if next, ok := a.nodes[n].next[s[i]]; ok {
+				n = next
+				break
+			}
+			if n == 0 {
+				break
+			}
+			n = a.nodes[n].fail
+		}
+		for o := n; o >= 0; o = a.nodes[o].dict {
+			for _, k := range a.nodes[o].out {
+				if !found(int(k), i+1-int(a.nodes[o].depth), i+1) {
+					return
+				}
+			}
+		}
+	}
+}
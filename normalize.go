@@ -0,0 +1,102 @@
+package unamex
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Normalization selects the steps applied to a username, and to the
+// blacklisted and reserved words, before they are compared. The steps
+// run in the order of the constants below.
+type Normalization uint8
+
+const (
+	// FoldCase lowercases the username. It is implied by the zero
+	// Normalization, so matching is case-insensitive by default.
+	FoldCase Normalization = 1 << iota
+	// StripSeparators removes the separators of the policy, so that
+	// "a.d.m.i.n" matches "admin".
+	StripSeparators
+	// Leet replaces digits and symbols written for letters, so that
+	// "4dm1n" matches "admin": 4 and @ for a, 8 for b, 3 for e, 9 for g,
+	// 1 and ! for i, 0 for o, 5 and $ for s, 7 and + for t.
+	Leet
+	// CollapseRepeats reduces runs of the same ASCII letter to one
+	// letter, so that "aaadmin" matches "admin".
+	CollapseRepeats
+
+	// NormalizeAll enables every step.
+	NormalizeAll = FoldCase | StripSeparators | Leet | CollapseRepeats
+)
+
+// normalizationNames are the names of the steps in configuration
+// documents, indexed by bit.
+var normalizationNames = [...]string{
+	"fold_case", "strip_separators", "leet", "collapse_repeats",
+}
+
+// leetSwap returns the letter that c stands for in leetspeak,
+// or c itself.
+func leetSwap(c byte) byte {
+	switch c {
+	case '4', '@':
+		return 'a'
+	case '8':
+		return 'b'
+	case '3':
+		return 'e'
+	case '9':
+		return 'g'
+	case '1', '!':
+		return 'i'
+	case '0':
+		return 'o'
+	case '5', '$':
+		return 's'
+	case '7', '+':
+		return 't'
+	}
+	return c
+}
+
+// Normalize applies the policy's Normalization to s and returns the
+// form that is looked up in the blacklist and reserved words.
+//
+// Example usage:
+//
+//	p := unamex.DefaultPolicy()
+//	p.Normalization = unamex.NormalizeAll
+//	p.Normalize("4.Dm1n") // "admin"
+func (p Policy) Normalize(s string) string {
+	n := p.Normalization
+	if n == 0 {
+		n = FoldCase
+	}
+
+	if n&FoldCase != 0 {
+		s = strings.ToLower(s)
+	}
+	if n&(StripSeparators|Leet|CollapseRepeats) == 0 {
+		return s
+	}
+
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			b = append(b, c)
+			continue
+		}
+		if n&StripSeparators != 0 && p.isSeparator(c) {
+			continue
+		}
+		if n&Leet != 0 {
+			c = leetSwap(c)
+		}
+		if n&CollapseRepeats != 0 && isLetter(c) && len(b) > 0 && b[len(b)-1] == c {
+			continue
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
@@ -0,0 +1,178 @@
+package unamex
+
+import "strings"
+
+// Policy configures the built-in length and format rules.
+// The zero value is not useful; start from DefaultPolicy and
+// adjust the fields that differ:
+//
+//	p := unamex.DefaultPolicy()
+//	p.MinLength, p.MaxLength = 3, 15
+//	p.Separators = "._-"
+//	p.MaxSeparators = 2
+//	u := unamex.NewWithPolicy(p, "gamer_tag")
+type Policy struct {
+	// MinLength and MaxLength bound the length of the username,
+	// in bytes, or in user-perceived characters when Unicode is set.
+	MinLength, MaxLength int
+
+	// Unicode allows letters and digits of any script instead of
+	// ASCII ones only, along with the combining marks that follow
+	// a letter. Lengths are counted in user-perceived characters,
+	// so that "José" is 4 characters long whichever way the accent
+	// is encoded.
+	Unicode bool
+
+	// Scripts restricts the letters of a Unicode username to the
+	// named scripts of the unicode package, such as "Latin" and
+	// "Greek". An empty list allows every script.
+	Scripts []string
+
+	// AllowMixedScripts permits a Unicode username to contain letters
+	// of more than one script. By default a name such as "pаypal",
+	// with a Cyrillic "а", is rejected; Han may still be combined with
+	// Hiragana and Katakana, Hangul or Bopomofo.
+	AllowMixedScripts bool
+
+	// Separators lists the ASCII characters allowed between letters
+	// and digits, such as "._-". An empty string allows no separators.
+	Separators string
+
+	// MaxSeparators limits the number of separators in a username.
+	// A negative value means no limit.
+	MaxSeparators int
+
+	// AllowLeadingSeparator and AllowTrailingSeparator permit a
+	// username to start or end with a separator.
+	AllowLeadingSeparator  bool
+	AllowTrailingSeparator bool
+
+	// AllowConsecutiveSeparators permits two or more separators in a row.
+	AllowConsecutiveSeparators bool
+
+	// AllowDigitsOnly permits usernames made up of digits only.
+	AllowDigitsOnly bool
+
+	// Blacklist replaces the built-in list of weak or common usernames
+	// when non-nil. Matching is case-insensitive. An entry may start
+	// with a match mode, such as "contains:admin"; see MatchMode.
+	Blacklist []string
+
+	// Reserved lists additional usernames that are rejected on top
+	// of the blacklist, such as product or feature names.
+	Reserved []string
+
+	// Categories adds reserved entries grouped by category, such as
+	// "product" or "features". The category of the entry a username
+	// matches is reported in ValidationError.Category.
+	Categories map[string][]string
+
+	// DisabledCategories lists the categories whose entries are not
+	// rejected: built-in categories such as CategoryRoutes, which
+	// apply when Blacklist is nil, or names of Categories.
+	DisabledCategories []string
+
+	// Live, when set, takes the place of Blacklist in the integrity
+	// rule, so that reloading it changes the list in use without
+	// rebuilding the validators. See LoadBlacklist.
+	Live *Blacklist
+
+	// Profanity enables the profanity rule when set.
+	Profanity *Profanity
+
+	// Routes enables the route collision rule when set.
+	Routes *Routes
+
+	// MinScore, when positive, rejects usernames whose Score is below
+	// it.
+	MinScore int
+
+	// Normalization selects how usernames, blacklisted and reserved
+	// words are normalized before they are compared. The zero value
+	// folds case only; NormalizeAll also catches "4.dm1n" and "aadmin".
+	Normalization Normalization
+
+	// Canonicalization selects which differences Canonicalize and
+	// Equivalent ignore on top of case and Unicode compatibility forms.
+	Canonicalization Canonicalization
+}
+
+// DefaultPolicy returns the policy used by New: between 5 and 30
+// characters, letters and digits with at most one period that is
+// neither leading nor trailing, and not digits only.
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:     5,
+		MaxLength:     30,
+		Separators:    string(separator),
+		MaxSeparators: 1,
+	}
+}
+
+// Validators returns the built-in validators configured by the policy:
+// length, format and blacklist checks, in that order, followed by the
+// profanity, route collision and score checks if enabled.
+func (p Policy) Validators() []Validator {
+	validators := []Validator{
+		p.validateRange,
+		p.validateFormat,
+		p.integrityValidator(),
+	}
+	if p.Profanity != nil {
+		validators = append(validators, p.profanityValidator())
+	}
+	if p.Routes != nil {
+		validators = append(validators, p.routeValidator())
+	}
+	if p.MinScore > 0 {
+		validators = append(validators, p.ScoreValidator(p.MinScore))
+	}
+	return validators
+}
+
+// integrityValidator returns the validator of the Live blacklist if
+// set, validateIntegrity for the built-in blacklist, or the validator
+// of the policy's Matcher otherwise.
+// A policy whose entries do not compile fails every username with
+// the compilation error.
+func (p Policy) integrityValidator() Validator {
+	if p.Live != nil {
+		return p.Live.validate
+	}
+	if p.Blacklist == nil && len(p.Reserved) == 0 &&
+		len(p.Categories) == 0 && len(p.DisabledCategories) == 0 &&
+		(p.Normalization == 0 || p.Normalization == FoldCase) {
+		return validateIntegrity
+	}
+
+	m, err := p.Matcher()
+	if err != nil {
+		return func(string) (bool, error) { return false, err }
+	}
+	return m.validate
+}
+
+// words returns the blacklist and reserved entries of the policy,
+// as listed by reservedWords.
+func (p Policy) words() []string {
+	list := p.reservedWords()
+	words := make([]string, len(list))
+	for i, w := range list {
+		words[i] = w.entry
+	}
+	return words
+}
+
+// isSeparator reports whether c is one of the policy's separators.
+func (p Policy) isSeparator(c byte) bool {
+	return strings.IndexByte(p.Separators, c) >= 0
+}
+
+// separator returns the separator used by the default suggestors:
+// the first allowed separator, or the period when none is allowed.
+func (p Policy) separator() byte {
+	if p.Separators == "" {
+		return separator
+	}
+	return p.Separators[0]
+}
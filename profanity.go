@@ -0,0 +1,202 @@
+package unamex
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Severity ranks how offensive a profane term is.
+type Severity uint8
+
+const (
+	// SeverityMild covers crude words such as "crap".
+	SeverityMild Severity = iota + 1
+	// SeverityStrong covers swear words.
+	SeverityStrong
+	// SeveritySevere covers slurs and the most offensive terms.
+	SeveritySevere
+)
+
+// severityNames are indexed by Severity.
+var severityNames = [...]string{
+	SeverityMild:   "mild",
+	SeverityStrong: "strong",
+	SeveritySevere: "severe",
+}
+
+// String returns the name of the severity, such as "strong".
+func (s Severity) String() string {
+	if int(s) < len(severityNames) && severityNames[s] != "" {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", uint8(s))
+}
+
+// ProfanityTerm is an offensive term searched for in usernames.
+type ProfanityTerm struct {
+	// Term is the offensive word, in lowercase.
+	Term string
+
+	// Severity ranks the term.
+	Severity Severity
+
+	// Lang is the ISO 639-1 code of the language of the term,
+	// such as "en".
+	Lang string
+
+	// Whole restricts the term to whole words instead of anywhere
+	// in a username, for short terms that are part of too many
+	// innocent words, such as "dick" in "dickerson". A word is the
+	// whole username, or a part of it between separators, with or
+	// without its leading and trailing digits.
+	Whole bool
+}
+
+// Profanity configures the profanity rule of a Policy. The zero value
+// rejects every bundled term, in any language.
+//
+// Terms are found anywhere in a username, after the username goes
+// through Normalization: "x.sh1thead" contains "shithead". To avoid the
+// Scunthorpe problem, an occurrence that lies within an exception,
+// such as "scunthorpe" or "cocktail", is not reported, and the short
+// terms that occur in too many names, such as "shit" in "yamashita",
+// only match whole words.
+type Profanity struct {
+	// MinSeverity is the least severity rejected; zero means
+	// SeverityMild, which rejects every term.
+	MinSeverity Severity
+
+	// Languages restricts the bundled terms to the given languages.
+	// An empty list keeps every language.
+	Languages []string
+
+	// Terms are added to the bundled terms.
+	Terms []ProfanityTerm
+
+	// Exceptions are added to the bundled exceptions: innocent words
+	// that contain a term.
+	Exceptions []string
+
+	// Normalization applies to usernames, terms and exceptions. The
+	// zero value folds case, strips separators and undoes leetspeak;
+	// CollapseRepeats is best left out, as it turns "ass" into "as".
+	Normalization Normalization
+}
+
+// profanityValidator compiles the profanity rule of the policy.
+func (p Policy) profanityValidator() Validator {
+	f := *p.Profanity
+	if f.Normalization == 0 {
+		f.Normalization = FoldCase | StripSeparators | Leet
+	}
+	np := Policy{Separators: p.Separators, Normalization: f.Normalization}
+
+	var terms []ProfanityTerm
+	for _, t := range profanity {
+		if len(f.Languages) == 0 || slices.Contains(f.Languages, t.Lang) {
+			terms = append(terms, t)
+		}
+	}
+	terms = slices.DeleteFunc(append(terms, f.Terms...), func(t ProfanityTerm) bool {
+		return t.Severity < f.MinSeverity
+	})
+	exceptions := append(slices.Clip(profanityExceptions), f.Exceptions...)
+
+	// Whole terms are looked up by word. The other terms come first
+	// in the automaton, exceptions after them.
+	whole := make(map[string]*ProfanityTerm)
+	var partial []*ProfanityTerm
+	var keywords []string
+	for i := range terms {
+		t := &terms[i]
+		if t.Whole {
+			if w := whole[np.Normalize(t.Term)]; w == nil || t.Severity > w.Severity {
+				whole[np.Normalize(t.Term)] = t
+			}
+			continue
+		}
+		partial = append(partial, t)
+		keywords = append(keywords, np.Normalize(t.Term))
+	}
+	for _, e := range exceptions {
+		keywords = append(keywords, np.Normalize(e))
+	}
+	ac := newAhoCorasick(keywords)
+
+	type span struct{ start, end int }
+	return func(name string) (bool, error) {
+		s := np.Normalize(name)
+		var worst *ProfanityTerm
+		for _, w := range p.profanityWords(name) {
+			if t := whole[np.Normalize(w)]; t != nil && (worst == nil || t.Severity > worst.Severity) {
+				worst = t
+			}
+		}
+
+		var found []span
+		var excepted []span
+		var hits []int
+		ac.scan(s, func(k, start, end int) bool {
+			if k >= len(partial) {
+				excepted = append(excepted, span{start, end})
+				return true
+			}
+			found = append(found, span{start, end})
+			hits = append(hits, k)
+			return true
+		})
+
+		// Report the most severe term that is not part of an exception.
+	term:
+		for i, t := range found {
+			for _, e := range excepted {
+				if e.start <= t.start && t.end <= e.end {
+					continue term
+				}
+			}
+			if t := partial[hits[i]]; worst == nil || t.Severity > worst.Severity {
+				worst = t
+			}
+		}
+		if worst != nil {
+			return false, &ValidationError{
+				Rule: RuleProfanity, Code: CodeProfane, Pos: -1,
+				Normalized: s, Match: worst.Term, Severity: worst.Severity,
+			}
+		}
+		return true, nil
+	}
+}
+
+// profanityWords returns the words of name that whole terms are
+// compared with: name itself and its parts between separators, each
+// also without its leading and trailing digits.
+func (p Policy) profanityWords(name string) []string {
+	words := []string{name}
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i < len(name) && !p.isSeparator(name[i]) {
+			continue
+		}
+		if w := name[start:i]; w != "" && w != name {
+			words = append(words, w)
+		}
+		start = i + 1
+	}
+	for _, w := range words {
+		if t := strings.Trim(w, "0123456789"); t != "" && t != w {
+			words = append(words, t)
+		}
+	}
+	return words
+}
+
+// parseSeverity returns the Severity named s.
+func parseSeverity(s string) (Severity, bool) {
+	i := slices.Index(severityNames[:], strings.ToLower(s))
+	if i <= 0 {
+		return 0, false
+	}
+	return Severity(i), true
+}
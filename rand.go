@@ -0,0 +1,152 @@
+package unamex
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+	"math/rand"
+	randv2 "math/rand/v2"
+	"sync"
+)
+
+// Rand is the source of random choices used by the suggestion helpers.
+// A *math/rand/v2.Rand satisfies it:
+//
+//	r := rand.New(rand.NewPCG(1, 2))
+//	s := unamex.VanishVowelRand(r, "username")
+type Rand interface {
+	// IntN returns a non-negative pseudo-random number in [0, n).
+	IntN(n int) int
+}
+
+// globalRand draws from the automatically seeded global source of
+// math/rand, which is safe for concurrent use.
+type globalRand struct{}
+
+// IntN returns rand.Intn(n).
+func (globalRand) IntN(n int) int {
+	return rand.Intn(n)
+}
+
+// lockedRand serializes access to a Rand that is not safe for
+// concurrent use, such as a *math/rand/v2.Rand.
+type lockedRand struct {
+	mu sync.Mutex
+	r  Rand
+}
+
+// IntN returns l.r.IntN(n) under the lock.
+func (l *lockedRand) IntN(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.IntN(n)
+}
+
+// WithRand makes the default suggestors and the suggestor order of the
+// Identity, and of Engines built from it, draw from r instead of the
+// global source. Access to r is serialized, so it does not need to be
+// safe for concurrent use. A nil r restores the global source.
+//
+// Two Identities given sources in the same state yield the same
+// suggestions for the same sequence of calls.
+func (u *Identity) WithRand(r Rand) *Identity {
+	u.rnd = nil
+	if r != nil {
+		u.rnd = &lockedRand{r: r}
+	}
+	return u
+}
+
+// WithSeed is WithRand with a PCG source seeded from seed, for
+// reproducible suggestions in tests and when replaying a report.
+//
+// Example usage:
+//
+//	u := New("john.smith").WithSeed(42)
+//	fmt.Println(u.Suggest(5)) // the same list on every run
+func (u *Identity) WithSeed(seed uint64) *Identity {
+	return u.WithRand(randv2.New(randv2.NewPCG(seed, seed)))
+}
+
+// HashRand is a Rand whose choices are derived from a keyed hash of a
+// username, so the same key, username, salt and attempt always yield
+// the same sequence of choices, on every server and across restarts.
+// The n-th choice is taken from HMAC-SHA256(key, username, salt,
+// attempt) expanded with SHA-256 in counter mode.
+//
+// A HashRand is not safe for concurrent use; create one per call.
+type HashRand struct {
+	seed    [sha256.Size]byte
+	counter uint64
+	buf     [sha256.Size]byte
+	off     int
+}
+
+// NewHashRand returns a HashRand for username. The salt separates
+// independent suggestion flows sharing a key, and attempt selects a
+// fresh, equally reproducible sequence, such as a second page of
+// suggestions.
+func NewHashRand(key []byte, username, salt string, attempt int) *HashRand {
+	mac := hmac.New(sha256.New, key)
+	var n [8]byte
+	for _, part := range []string{username, salt} {
+		// Length-prefix each part so ("ab", "c") and ("a", "bc") differ
+		binary.BigEndian.PutUint64(n[:], uint64(len(part)))
+		mac.Write(n[:])
+		mac.Write([]byte(part))
+	}
+	binary.BigEndian.PutUint64(n[:], uint64(attempt))
+	mac.Write(n[:])
+
+	h := &HashRand{off: sha256.Size}
+	mac.Sum(h.seed[:0])
+	return h
+}
+
+// IntN returns a number in [0, n) derived from the next 8 bytes of
+// the hash stream. It panics if n <= 0.
+func (h *HashRand) IntN(n int) int {
+	if n <= 0 {
+		panic("unamex: invalid argument to IntN")
+	}
+	if h.off+8 > len(h.buf) {
+		var block [sha256.Size + 8]byte
+		copy(block[:], h.seed[:])
+		binary.BigEndian.PutUint64(block[sha256.Size:], h.counter)
+		h.buf = sha256.Sum256(block[:])
+		h.counter++
+		h.off = 0
+	}
+	x := binary.BigEndian.Uint64(h.buf[h.off:])
+	h.off += 8
+
+	// Map x onto [0, n) with a multiply-shift; the bias is
+	// negligible for the small ranges the helpers use.
+	hi, _ := bits.Mul64(x, uint64(n))
+	return int(hi)
+}
+
+// WithDeterministic switches the Identity, and Engines built from it,
+// to deterministic suggestions: each call draws from a HashRand keyed
+// with key and salt and seeded with the username, instead of a shared
+// source. Two replicas configured with the same key, salt, policy and
+// suggestors produce the identical ordered suggestion list for the
+// same username, without storing a seed. Custom Suggestor functions
+// that make their own random choices are not affected.
+//
+// A nil key turns deterministic mode off again.
+//
+// Example usage:
+//
+//	u := New("john.smith").WithDeterministic(secret, "sso")
+//	fmt.Println(u.Suggest(5)) // the same list on every server
+func (u *Identity) WithDeterministic(key []byte, salt string) *Identity {
+	u.hashKey = nil
+	u.hashSalt = salt
+	if key != nil {
+		u.hashKey = bytes.Clone(key)
+	}
+	return u
+}
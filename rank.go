@@ -0,0 +1,90 @@
+package unamex
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// Suggestion is a suggested username with its provenance and rating,
+// as returned by SuggestDetailed.
+type Suggestion struct {
+	// Candidate is the suggested username.
+	Candidate string
+
+	// Suggestor is the name of the suggestor that produced it, such as
+	// "VanishVowel", or "custom" for a Suggestor function.
+	Suggestor string
+
+	// Score is the Score total of the candidate under the policy.
+	Score int
+
+	// Distance is the EditDistance from the original username.
+	Distance int
+}
+
+// RankFunc orders suggestions: it returns a negative number when a
+// should come before b, a positive number when after, and zero to keep
+// their order.
+type RankFunc func(a, b Suggestion) int
+
+// RankByScore ranks the best scoring suggestions first, then the
+// closest to the original username. It is the default RankFunc.
+func RankByScore(a, b Suggestion) int {
+	if c := cmp.Compare(b.Score, a.Score); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Distance, b.Distance)
+}
+
+// RankByDistance ranks the suggestions closest to the original
+// username first, then the best scoring.
+func RankByDistance(a, b Suggestion) int {
+	if c := cmp.Compare(a.Distance, b.Distance); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.Score, a.Score)
+}
+
+// WithRanking sets the order of the suggestions of SuggestDetailed.
+// A nil rank restores RankByScore.
+//
+// Example usage:
+//
+//	u := unamex.New("sarah").WithRanking(unamex.RankByDistance)
+//	for _, s := range u.SuggestDetailed(5) {
+//		fmt.Println(s.Candidate, s.Suggestor, s.Score, s.Distance)
+//	}
+func (u *Identity) WithRanking(rank RankFunc) *Identity {
+	u.rank = rank
+	return u
+}
+
+// SuggestDetailed generates suggestions as Suggest does, and returns
+// them with the suggestor that produced them, their score and their
+// edit distance from the username, best ranked first.
+func (u *Identity) SuggestDetailed(capacity int) []Suggestion {
+	suggestions, _ := u.engine().SuggestDetailed(context.Background(), u.uname, capacity)
+	return suggestions
+}
+
+// SuggestDetailed generates up to n alternatives to name, as Suggest,
+// and returns them rated and ranked as Identity.SuggestDetailed. If ctx
+// is done, the suggestions gathered so far are returned with the
+// context's error.
+func (e *Engine) SuggestDetailed(ctx context.Context, name string, n int) ([]Suggestion, error) {
+	suggestions, err := e.suggest(ctx, name, n)
+	words := e.policy.exactWords()
+	for i := range suggestions {
+		s := &suggestions[i]
+		s.Score = e.policy.score(s.Candidate, words).Total
+		s.Distance = EditDistance(name, s.Candidate)
+	}
+
+	rank := e.rank
+	if rank == nil {
+		rank = RankByScore
+	}
+	slices.SortStableFunc(suggestions, rank)
+	return suggestions, err
+}
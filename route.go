@@ -0,0 +1,118 @@
+package unamex
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Routes configures the route collision rule of a Policy, for usernames
+// that become URL paths such as example.com/<name>, or directory names.
+// The zero value rejects the built-in well-known URL segments and
+// reserved file names.
+//
+// Patterns are the routes of the application, either plain paths such
+// as "/api/v1" or "docs", or http.ServeMux patterns such as
+// "GET example.com/static/{path...}"; as in a ServeMux, the text before
+// the first slash is a host. A username collides with the first segment
+// of a pattern when it is a literal: "/settings/{id}" reserves
+// "settings", while "/{user}" reserves nothing. Comparison is
+// case-insensitive, as on most file systems.
+type Routes struct {
+	// Patterns lists the routes of the application.
+	Patterns []string
+
+	// AllowWellKnown accepts the built-in well-known URL segments,
+	// such as "api", "static", "favicon.ico" and ".well-known".
+	AllowWellKnown bool
+
+	// AllowReservedFilenames accepts the file names reserved by
+	// Windows, such as "con", "nul" or "lpt1.txt", as well as "."
+	// and "..".
+	AllowReservedFilenames bool
+}
+
+// routeSegment returns the first path segment of a route or
+// http.ServeMux pattern, or "" if the pattern does not start with a
+// literal segment.
+func routeSegment(pattern string) (string, error) {
+	p := strings.TrimSpace(pattern)
+	if p == "" {
+		return "", fmt.Errorf("route %q is empty", pattern)
+	}
+	// Leave out the method and the host of ServeMux patterns.
+	if i := strings.IndexAny(p, " \t"); i >= 0 {
+		p = strings.TrimLeft(p[i:], " \t")
+	}
+	if i := strings.IndexByte(p, '/'); i > 0 {
+		p = p[i:]
+	}
+
+	p = strings.TrimPrefix(p, "/")
+	segment, _, _ := strings.Cut(p, "/")
+	if strings.ContainsAny(segment, "{}") {
+		if segment[0] != '{' || segment[len(segment)-1] != '}' ||
+			strings.Count(segment, "{") != 1 || strings.Count(segment, "}") != 1 {
+			return "", fmt.Errorf("route %q: a wildcard must be a whole segment", pattern)
+		}
+		return "", nil
+	}
+	segment, err := url.PathUnescape(segment)
+	if err != nil {
+		return "", fmt.Errorf("route %q: %w", pattern, err)
+	}
+	return strings.ToLower(segment), nil
+}
+
+// reservedFilename reports whether name is reserved by Windows, which
+// ignores the extension and trailing dots and spaces of device names,
+// and returns the reserved name.
+func reservedFilename(name string) (string, bool) {
+	if name == "." || name == ".." {
+		return name, true
+	}
+	base, _, _ := strings.Cut(strings.TrimRight(name, ". "), ".")
+	for _, r := range reservedFilenames {
+		if base == r {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// routeValidator compiles the route collision rule of the policy.
+// A pattern that does not parse fails every username with the error.
+func (p Policy) routeValidator() Validator {
+	r := *p.Routes
+	segments := make(map[string]string)
+	if !r.AllowWellKnown {
+		for _, s := range wellKnownSegments {
+			segments[s] = s
+		}
+	}
+	for _, pattern := range r.Patterns {
+		s, err := routeSegment(pattern)
+		if err != nil {
+			err = fmt.Errorf("unamex: %w", err)
+			return func(string) (bool, error) { return false, err }
+		}
+		if s != "" {
+			segments[s] = pattern
+		}
+	}
+
+	return func(s string) (bool, error) {
+		name := strings.ToLower(s)
+		match, ok := segments[name]
+		if !ok && !r.AllowReservedFilenames {
+			match, ok = reservedFilename(name)
+		}
+		if ok {
+			return false, &ValidationError{
+				Rule: RuleRoute, Code: CodeRouteCollision, Pos: -1,
+				Normalized: name, Match: match,
+			}
+		}
+		return true, nil
+	}
+}
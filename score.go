@@ -0,0 +1,332 @@
+package unamex
+
+import (
+	"math"
+	"math/bits"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Score rates the quality of a username from 0 to 100: long, varied,
+// pronounceable names that look like nothing common score high, while
+// names such as "aaaaa1" or "user12345" score low. Total is the sum of
+// the points of the factors, lowered by the weakest of them.
+type Score struct {
+	Total   int
+	Factors []ScoreFactor
+}
+
+// ScoreFactor is one contribution to a Score.
+type ScoreFactor struct {
+	// Name identifies the factor: "length", "entropy", "digits",
+	// "repeats", "words", "pronounceability" or "blacklist".
+	Name string
+
+	// Value rates the username on the factor, from 0 (worst) to 1.
+	Value float64
+
+	// Weight is the number of points the factor is worth.
+	Weight int
+}
+
+// Points returns the points the factor contributes to the total.
+func (f ScoreFactor) Points() float64 {
+	return f.Value * float64(f.Weight)
+}
+
+// Factor returns the factor of the given name, or the zero
+// ScoreFactor if there is none.
+func (s Score) Factor(name string) ScoreFactor {
+	for _, f := range s.Factors {
+		if f.Name == name {
+			return f
+		}
+	}
+	return ScoreFactor{}
+}
+
+// Score rates the quality of name under the policy, whose separators
+// are ignored and whose blacklisted and reserved words are the ones
+// name should not resemble.
+//
+// Example usage:
+//
+//	p := unamex.DefaultPolicy()
+//	p.Score("user12345").Total   // low
+//	p.Score("sarah.adams").Total // high
+func (p Policy) Score(name string) Score {
+	return p.score(name, p.exactWords())
+}
+
+// score implements Score, with the exact words of the policy
+// returned by exactWords.
+func (p Policy) score(name string, words []exactWord) Score {
+	var chars []rune
+	for _, r := range strings.ToLower(name) {
+		if r < utf8.RuneSelf && p.isSeparator(byte(r)) {
+			continue
+		}
+		chars = append(chars, r)
+	}
+	letters := make([]rune, 0, len(chars))
+	for _, r := range chars {
+		if unicode.IsLetter(r) {
+			letters = append(letters, r)
+		}
+	}
+
+	factors := []ScoreFactor{
+		{Name: "length", Value: scoreLength(len(chars)), Weight: 15},
+		{Name: "entropy", Value: scoreEntropy(chars), Weight: 15},
+		{Name: "digits", Value: scoreDigits(chars), Weight: 15},
+		{Name: "repeats", Value: scoreRepeats(chars), Weight: 15},
+		{Name: "words", Value: scoreWords(string(letters)), Weight: 10},
+		{Name: "pronounceability", Value: scorePronounceability(letters), Weight: 10},
+		{Name: "blacklist", Value: scoreBlacklist(string(chars), string(letters), words), Weight: 20},
+	}
+
+	if len(chars) == 0 {
+		return Score{Factors: factors}
+	}
+	var points float64
+	weakest := 1.0
+	for _, f := range factors {
+		points += f.Points()
+		weakest = min(weakest, f.Value)
+	}
+	// A name bad in one respect is bad, however good it is in others.
+	return Score{
+		Total:   int(math.Round(points * (0.5 + weakest/2))),
+		Factors: factors,
+	}
+}
+
+// clamp01 bounds x to [0, 1].
+func clamp01(x float64) float64 {
+	return max(0, min(1, x))
+}
+
+// scoreLength favors 9 to 20 characters.
+func scoreLength(n int) float64 {
+	if n > 20 {
+		return clamp01(1 - float64(n-20)/20)
+	}
+	return clamp01(float64(n-3) / 6)
+}
+
+// scoreEntropy is the Shannon entropy of the characters, relative to
+// that of as many distinct characters.
+func scoreEntropy(chars []rune) float64 {
+	if len(chars) < 2 {
+		return 0
+	}
+	// Sum in order of first appearance, for a reproducible result.
+	index := make(map[rune]int)
+	var counts []int
+	for _, r := range chars {
+		i, ok := index[r]
+		if !ok {
+			i = len(counts)
+			index[r] = i
+			counts = append(counts, 0)
+		}
+		counts[i]++
+	}
+	var h float64
+	for _, c := range counts {
+		q := float64(c) / float64(len(chars))
+		h -= q * math.Log2(q)
+	}
+	return clamp01(h / math.Log2(float64(len(chars))))
+}
+
+// scoreDigits accepts up to a fifth of digits, and nothing from
+// seven tenths on.
+func scoreDigits(chars []rune) float64 {
+	if len(chars) == 0 {
+		return 0
+	}
+	var digits int
+	for _, r := range chars {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	ratio := float64(digits) / float64(len(chars))
+	return clamp01(1 - (ratio-0.2)/0.5)
+}
+
+// scoreRepeats penalizes characters equal to the previous one, and
+// digits following the previous digit up or down, as in "12345".
+func scoreRepeats(chars []rune) float64 {
+	if len(chars) < 2 {
+		return 1
+	}
+	var repeats int
+	for i := 1; i < len(chars); i++ {
+		a, b := chars[i-1], chars[i]
+		if a == b || unicode.IsDigit(a) && unicode.IsDigit(b) && (b == a+1 || b == a-1) {
+			repeats++
+		}
+	}
+	return clamp01(1 - 2*float64(repeats)/float64(len(chars)-1))
+}
+
+// scoreWords is the share of letters outside of generic words such as
+// "user" or "test".
+func scoreWords(letters string) float64 {
+	if letters == "" {
+		return 1
+	}
+	covered := make([]bool, len(letters))
+	for _, w := range genericWords {
+		for i := 0; ; {
+			j := strings.Index(letters[i:], w)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(w); k++ {
+				covered[k] = true
+			}
+			i += j + 1
+		}
+	}
+	var n int
+	for _, c := range covered {
+		if c {
+			n++
+		}
+	}
+	return 1 - float64(n)/float64(len(letters))
+}
+
+// scorePronounceability penalizes runs of more than two vowels or
+// three consonants, and letters without any vowel. Letters outside of
+// the Latin alphabet are not rated.
+func scorePronounceability(letters []rune) float64 {
+	var latin, bad, vowels, run int
+	var inVowels bool
+	for _, r := range letters {
+		if r >= utf8.RuneSelf {
+			continue
+		}
+		latin++
+		vowel := isVowel(byte(r)) || r == 'y'
+		if vowel {
+			vowels++
+		}
+		if vowel != inVowels {
+			inVowels, run = vowel, 0
+		}
+		run++
+		if vowel && run > 2 || !vowel && run > 3 {
+			bad++
+		}
+	}
+	if latin == 0 {
+		return 1
+	}
+	if vowels == 0 {
+		return 0
+	}
+	return clamp01(1 - 2*float64(bad)/float64(latin))
+}
+
+// exactWord is an exact blacklisted or reserved word, lowercased,
+// with its charMask.
+type exactWord struct {
+	text string
+	mask uint64
+}
+
+// builtinExactWords are the exact words of the built-in blacklist,
+// shared by the policies that do not change it.
+var builtinExactWords = sync.OnceValue(Policy{}.listExactWords)
+
+// exactWords returns the exact words of the policy, as compared by
+// scoreBlacklist.
+func (p Policy) exactWords() []exactWord {
+	if p.Blacklist == nil && len(p.Reserved) == 0 &&
+		len(p.Categories) == 0 && len(p.DisabledCategories) == 0 {
+		return builtinExactWords()
+	}
+	return p.listExactWords()
+}
+
+func (p Policy) listExactWords() []exactWord {
+	var words []exactWord
+	for _, w := range p.words() {
+		if mode, text := parseEntry(w); mode == MatchExact {
+			text = strings.ToLower(text)
+			words = append(words, exactWord{text: text, mask: charMask(text)})
+		}
+	}
+	return words
+}
+
+// charMask returns the set of the bytes of an ASCII string, folded
+// into 64 bits, or 0 for any other string. A string needs at least one
+// edit per member of the set of another that it lacks, so the masks
+// give a lower bound of the edit distance.
+func charMask(s string) uint64 {
+	var m uint64
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return 0
+		}
+		m |= 1 << (s[i] % 64)
+	}
+	return m
+}
+
+// scoreBlacklist rates the edit distance of the name, with and without
+// its digits and symbols, to the nearest of the exact words: 0 for a
+// match, 1 from a distance of 3 on.
+func scoreBlacklist(chars, letters string, words []exactWord) float64 {
+	const far = 3
+	nearest := far
+	var buf editBuffer
+	for _, s := range []string{chars, letters} {
+		if s == "" {
+			continue
+		}
+		mask := charMask(s)
+		for _, w := range words {
+			if mask != 0 && w.mask != 0 &&
+				max(bits.OnesCount64(mask&^w.mask), bits.OnesCount64(w.mask&^mask)) >= nearest {
+				continue
+			}
+			nearest = min(nearest, buf.distance(s, w.text, nearest-1))
+			if nearest == 0 {
+				return 0
+			}
+		}
+	}
+	return float64(nearest) / far
+}
+
+// ScoreValidator returns a validator rejecting usernames whose Score
+// under the policy is below minScore.
+//
+// The failure is a *ValidationError with Rule RuleQuality, Code
+// CodeLowScore, the score in Score and minScore in Min.
+func (p Policy) ScoreValidator(minScore int) Validator {
+	words := p.exactWords()
+	return func(s string) (bool, error) {
+		if score := p.score(s, words).Total; score < minScore {
+			return false, &ValidationError{
+				Rule: RuleQuality, Code: CodeLowScore, Pos: -1,
+				Min: minScore, Score: score,
+			}
+		}
+		return true, nil
+	}
+}
+
+// Score rates the quality of the current username under the policy
+// of the Identity.
+func (u *Identity) Score() Score {
+	return u.policy.Score(u.uname)
+}
@@ -0,0 +1,339 @@
+package unamex
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// EditDistance returns the Damerau–Levenshtein distance between a and
+// b: the least number of rune insertions, deletions, substitutions and
+// transpositions of adjacent runes turning a into b. Unlike the
+// restricted variant, it is a metric, so it can back a SimilarIndex.
+//
+// Example usage:
+//
+//	unamex.EditDistance("john.smith", "jonh.smith") // 1
+//	unamex.EditDistance("ca", "abc")                // 2
+func EditDistance(a, b string) int {
+	var buf editBuffer
+	return buf.distance(a, b, len(a)+len(b))
+}
+
+// editBuffer holds the scratch space of EditDistance, so that an index
+// lookup computing many distances allocates once.
+type editBuffer struct {
+	a, b    []int32
+	last    []int32
+	d       []int32
+	symbols map[rune]int32
+}
+
+// distance returns EditDistance(a, b), or limit+1 if it is above
+// limit.
+func (e *editBuffer) distance(a, b string, limit int) int {
+	if a == b {
+		return 0
+	}
+	if len(a) > len(b) && utf8.RuneCountInString(a)-len(b) > limit ||
+		len(b) > len(a) && utf8.RuneCountInString(b)-len(a) > limit {
+		return limit + 1
+	}
+
+	// Compare symbols: the bytes of ASCII strings, or else the runes
+	// numbered in order of appearance.
+	e.a, e.b = e.a[:0], e.b[:0]
+	var alphabet int
+	if isASCII(a) && isASCII(b) {
+		for i := 0; i < len(a); i++ {
+			e.a = append(e.a, int32(a[i]))
+		}
+		for i := 0; i < len(b); i++ {
+			e.b = append(e.b, int32(b[i]))
+		}
+		alphabet = utf8.RuneSelf
+	} else {
+		if e.symbols == nil {
+			e.symbols = make(map[rune]int32)
+		}
+		clear(e.symbols)
+		symbol := func(r rune) int32 {
+			n, ok := e.symbols[r]
+			if !ok {
+				n = int32(len(e.symbols))
+				e.symbols[r] = n
+			}
+			return n
+		}
+		for _, r := range a {
+			e.a = append(e.a, symbol(r))
+		}
+		for _, r := range b {
+			e.b = append(e.b, symbol(r))
+		}
+		alphabet = len(e.symbols)
+	}
+
+	la, lb := len(e.a), len(e.b)
+	if la == 0 || lb == 0 {
+		return la + lb
+	}
+	e.last = grow(e.last, alphabet)
+	e.d = grow(e.d, (la+2)*(lb+2))
+	return damerauLevenshtein(e.a, e.b, e.last, e.d, int32(limit))
+}
+
+// grow returns a zeroed slice of n elements, reusing s if it is large
+// enough.
+func grow(s []int32, n int) []int32 {
+	if cap(s) < n {
+		return make([]int32, n)
+	}
+	s = s[:n]
+	clear(s)
+	return s
+}
+
+// damerauLevenshtein implements editBuffer.distance with the algorithm
+// of Lowrance and Wagner. last is indexed by the symbols of a and b,
+// and d holds the (len(a)+2)×(len(b)+2) distance matrix; both are
+// zeroed. As the minimum of a row never decreases, it stops at the
+// first row above limit.
+func damerauLevenshtein(a, b, last, d []int32, limit int32) int {
+	la, lb := len(a), len(b)
+
+	// d has a border of inf.
+	w := lb + 2
+	inf := int32(la + lb)
+	d[0] = inf
+	for i := 0; i <= la; i++ {
+		d[(i+1)*w] = inf
+		d[(i+1)*w+1] = int32(i)
+	}
+	for j := 0; j <= lb; j++ {
+		d[j+1] = inf
+		d[w+j+1] = int32(j)
+	}
+
+	for i := 1; i <= la; i++ {
+		var db int32
+		row, prev := d[(i+1)*w:(i+2)*w], d[i*w:(i+1)*w]
+		least := row[1]
+		for j := 1; j <= lb; j++ {
+			i1, j1 := last[b[j-1]], db
+			var cost int32 = 1
+			if a[i-1] == b[j-1] {
+				cost, db = 0, int32(j)
+			}
+			row[j+1] = min(
+				prev[j]+cost,
+				row[j]+1,
+				prev[j+1]+1,
+				d[int(i1)*w+int(j1)]+(int32(i)-i1-1)+1+(int32(j)-j1-1),
+			)
+			least = min(least, row[j+1])
+		}
+		if least > limit {
+			return int(limit) + 1
+		}
+		last[a[i-1]] = int32(i)
+	}
+	return int(min(d[(la+1)*w+lb+1], limit+1))
+}
+
+// SimilarName is a protected username close to a candidate.
+type SimilarName struct {
+	Name     string
+	Distance int
+}
+
+// SimilarNames finds protected usernames close to a candidate, such as
+// those of verified accounts that new ones must not impersonate.
+type SimilarNames interface {
+	// FindSimilar returns the protected usernames within maxDistance
+	// of name, nearest first.
+	FindSimilar(ctx context.Context, name string, maxDistance int) ([]SimilarName, error)
+}
+
+// SimilarIndex is an in-memory SimilarNames backed by a BK-tree, which
+// only compares a candidate with a small part of the protected names:
+// a lookup within distance 1 or 2 stays fast for hundreds of thousands
+// of names. Names are compared by the Skeleton of their normalized
+// form under the policy of the index, so "John.Smith", "jonh.smith"
+// and "john.srnith" are all within distance 1 of "john.smith".
+// It is safe for concurrent use.
+type SimilarIndex struct {
+	policy Policy
+
+	// mu guards the tree; buf is the scratch space of insertions.
+	mu    sync.RWMutex
+	nodes []bkNode
+	size  int
+	buf   editBuffer
+}
+
+// bkNode holds the names sharing a key; its children are at the
+// distance of their edge from the key, reach being the longest.
+type bkNode struct {
+	key      string
+	names    []string
+	children []bkEdge
+	reach    int32
+}
+
+type bkEdge struct {
+	distance int32
+	node     int32
+}
+
+// NewSimilarIndex returns a SimilarIndex holding the given usernames,
+// normalized as by p.Normalize.
+//
+// Example usage:
+//
+//	verified := unamex.NewSimilarIndex(unamex.DefaultPolicy(), "john.smith", "moree")
+//	u := unamex.New("jonh.smith").WithSimilarity(verified, 1)
+//	err := u.ValidateContext(ctx) // username is too similar to "john.smith"
+func NewSimilarIndex(p Policy, names ...string) *SimilarIndex {
+	return (&SimilarIndex{policy: p}).Add(names...)
+}
+
+// key returns the form of s compared by the index.
+func (x *SimilarIndex) key(s string) string {
+	return Skeleton(x.policy.Normalize(s))
+}
+
+// Add protects usernames.
+func (x *SimilarIndex) Add(names ...string) *SimilarIndex {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, name := range names {
+		x.insert(x.key(name), name)
+	}
+	return x
+}
+
+func (x *SimilarIndex) insert(key, name string) {
+	x.size++
+	if len(x.nodes) == 0 {
+		x.nodes = append(x.nodes, bkNode{key: key, names: []string{name}})
+		return
+	}
+
+	n := int32(0)
+walk:
+	for {
+		d := int32(x.buf.distance(key, x.nodes[n].key, len(key)+len(x.nodes[n].key)))
+		if d == 0 {
+			if !slices.Contains(x.nodes[n].names, name) {
+				x.nodes[n].names = append(x.nodes[n].names, name)
+			} else {
+				x.size--
+			}
+			return
+		}
+		for _, e := range x.nodes[n].children {
+			if e.distance == d {
+				n = e.node
+				continue walk
+			}
+		}
+		child := int32(len(x.nodes))
+		x.nodes = append(x.nodes, bkNode{key: key, names: []string{name}})
+		x.nodes[n].children = append(x.nodes[n].children, bkEdge{distance: d, node: child})
+		x.nodes[n].reach = max(x.nodes[n].reach, d)
+		return
+	}
+}
+
+// Len returns the number of protected usernames.
+func (x *SimilarIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.size
+}
+
+// FindSimilar returns the protected usernames within maxDistance of
+// name, nearest first.
+func (x *SimilarIndex) FindSimilar(ctx context.Context, name string, maxDistance int) ([]SimilarName, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := x.key(name)
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.nodes) == 0 {
+		return nil, nil
+	}
+
+	var found []SimilarName
+	var buf editBuffer
+	stack := []int32{0}
+	for len(stack) > 0 {
+		n := &x.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+
+		// Beyond reach+maxDistance, neither n nor its children match:
+		// stop computing the distance there.
+		d := buf.distance(key, n.key, int(n.reach)+maxDistance)
+		if d <= maxDistance {
+			for _, name := range n.names {
+				found = append(found, SimilarName{Name: name, Distance: d})
+			}
+		}
+		// By the triangle inequality, matches below a child lie within
+		// maxDistance of d from the key of n.
+		for _, e := range n.children {
+			if int(e.distance) >= d-maxDistance && int(e.distance) <= d+maxDistance {
+				stack = append(stack, e.node)
+			}
+		}
+	}
+
+	slices.SortFunc(found, func(a, b SimilarName) int {
+		if a.Distance != b.Distance {
+			return a.Distance - b.Distance
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return found, nil
+}
+
+// SimilarityValidator returns a validator rejecting usernames within
+// maxDistance of a username known to protected, as reported by
+// FindSimilar; a maxDistance of 0 only rejects names with the same
+// normalized form. A username is not similar to itself: a protected
+// username equal to the one validated, ignoring case, is not reported,
+// except when filtering suggestions, which never return a protected
+// name.
+//
+// The failure is a *ValidationError with Rule RuleSimilarity, Code
+// CodeTooSimilar, the nearest protected username in Match and its
+// distance in Distance.
+func SimilarityValidator(protected SimilarNames, maxDistance int) ValidatorCtx {
+	return func(ctx context.Context, s string) error {
+		found, err := protected.FindSimilar(ctx, s, maxDistance)
+		if err != nil {
+			return err
+		}
+		for _, f := range found {
+			if isSuggestion(ctx) || !strings.EqualFold(f.Name, s) {
+				return &ValidationError{
+					Rule: RuleSimilarity, Code: CodeTooSimilar, Pos: -1,
+					Match: f.Name, Distance: f.Distance,
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// WithSimilarity adds the SimilarityValidator of protected to the
+// context-aware validators of the Identity. It is run by
+// ValidateContext and filters the suggestions.
+func (u *Identity) WithSimilarity(protected SimilarNames, maxDistance int) *Identity {
+	return u.AddValidatorCtx(SimilarityValidator(protected, maxDistance))
+}
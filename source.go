@@ -0,0 +1,315 @@
+package unamex
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BlacklistSource provides the entries of a blacklist, in the syntax
+// of Policy.Blacklist. Entries is called on every load of a Blacklist,
+// so a source may return different entries over time.
+type BlacklistSource interface {
+	Entries() ([]string, error)
+}
+
+// BlacklistFunc adapts an ordinary function to the BlacklistSource
+// interface.
+type BlacklistFunc func() ([]string, error)
+
+// Entries calls f().
+func (f BlacklistFunc) Entries() ([]string, error) {
+	return f()
+}
+
+// DefaultBlacklist returns the source of the built-in blacklist.
+func DefaultBlacklist() BlacklistSource {
+	return BlacklistFunc(func() ([]string, error) {
+		return slices.Clone(blacklist), nil
+	})
+}
+
+// FileBlacklist returns a source reading a newline-delimited file,
+// with blank lines and lines starting with '#' ignored. The file is
+// read again on every load, and is watched by Blacklist.Watch.
+func FileBlacklist(path string) BlacklistSource {
+	return fileSource(path)
+}
+
+type fileSource string
+
+func (f fileSource) Entries() ([]string, error) {
+	return readWordFile(string(f))
+}
+
+func (f fileSource) files() []string {
+	return []string{string(f)}
+}
+
+// ReaderBlacklist returns a source reading r, in the format of
+// FileBlacklist. The reader is consumed by the first load; later
+// loads return the same entries.
+func ReaderBlacklist(r io.Reader) BlacklistSource {
+	return BlacklistFunc(sync.OnceValues(func() ([]string, error) {
+		return readWords(r)
+	}))
+}
+
+// BlacklistSet is an in-memory BlacklistSource whose entries can be
+// changed at run time; call Blacklist.Reload to apply the changes.
+// It is safe for concurrent use.
+type BlacklistSet struct {
+	mu      sync.RWMutex
+	entries map[string]bool
+}
+
+// NewBlacklistSet returns a BlacklistSet holding the given entries.
+func NewBlacklistSet(entries ...string) *BlacklistSet {
+	s := &BlacklistSet{entries: make(map[string]bool, len(entries))}
+	return s.Add(entries...)
+}
+
+// Add adds entries to the set.
+func (s *BlacklistSet) Add(entries ...string) *BlacklistSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		s.entries[e] = true
+	}
+	return s
+}
+
+// Remove removes entries from the set.
+func (s *BlacklistSet) Remove(entries ...string) *BlacklistSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		delete(s.entries, e)
+	}
+	return s
+}
+
+// Entries returns the entries of the set, sorted.
+func (s *BlacklistSet) Entries() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]string, 0, len(s.entries))
+	for e := range s.entries {
+		list = append(list, e)
+	}
+	slices.Sort(list)
+	return list, nil
+}
+
+// MergeBlacklists returns a source with the entries of every given
+// source, in order and without duplicates. It fails if any of the
+// sources fails.
+func MergeBlacklists(sources ...BlacklistSource) BlacklistSource {
+	return mergedSource(sources)
+}
+
+type mergedSource []BlacklistSource
+
+func (m mergedSource) Entries() ([]string, error) {
+	var list []string
+	seen := make(map[string]bool)
+	for _, s := range m {
+		entries, err := s.Entries()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !seen[e] {
+				seen[e] = true
+				list = append(list, e)
+			}
+		}
+	}
+	return list, nil
+}
+
+func (m mergedSource) files() []string {
+	var files []string
+	for _, s := range m {
+		if f, ok := s.(interface{ files() []string }); ok {
+			files = append(files, f.files()...)
+		}
+	}
+	return files
+}
+
+// Blacklist is a compiled blacklist loaded from a BlacklistSource,
+// which can be reloaded while it is in use: a reload compiles the new
+// entries aside and swaps them in atomically, so validations never
+// wait for it and always see either the old or the new list.
+//
+// Set it as Policy.Live to use it in the built-in validators:
+//
+//	src := unamex.MergeBlacklists(unamex.DefaultBlacklist(),
+//		unamex.FileBlacklist("/etc/myapp/reserved.txt"))
+//	p := unamex.DefaultPolicy()
+//	p.Live, err = unamex.LoadBlacklist(p, src)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	go p.Live.Watch(ctx, 30*time.Second, func(err error) {
+//		log.Println("blacklist reload:", err)
+//	})
+//	u := unamex.NewWithPolicy(p, "gamer_tag")
+type Blacklist struct {
+	policy Policy
+	source BlacklistSource
+
+	// files are the files read by the source, and loaded their
+	// state as of the last reload.
+	files  []string
+	loaded []fileState
+
+	// mu serializes reloads and guards loaded; matcher is read
+	// without it.
+	mu      sync.Mutex
+	matcher atomic.Pointer[Matcher]
+}
+
+// LoadBlacklist loads the entries of source and compiles them, along
+// with the reserved words, categories, normalization and separators of
+// the policy. Entries that are words of the built-in blacklist keep
+// their category, and are left out when it is disabled.
+func LoadBlacklist(p Policy, source BlacklistSource) (*Blacklist, error) {
+	p.Live = nil
+	b := &Blacklist{policy: p, source: source}
+	if f, ok := source.(interface{ files() []string }); ok {
+		b.files = f.files()
+	}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload loads the entries of the source again and swaps them in.
+// On failure the current entries stay in use.
+func (b *Blacklist) Reload() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Stat before reading, so that a change made while reading
+	// triggers another reload, and record it only on success, so that
+	// Watch retries a failed one.
+	loaded := stat(b.files)
+	entries, err := b.source.Entries()
+	if err != nil {
+		return err
+	}
+	// Entries that are built-in words keep their category, so that
+	// the disabled categories of the policy apply to them.
+	p := b.policy
+	p.Blacklist = []string{}
+	var words []reservedWord
+	for _, e := range entries {
+		c := builtinCategory[e]
+		if c != "" && slices.Contains(p.DisabledCategories, c) {
+			continue
+		}
+		words = append(words, reservedWord{entry: e, category: c})
+	}
+	m, err := p.compile(append(words, p.reservedWords()...))
+	if err != nil {
+		return err
+	}
+	b.matcher.Store(m)
+	b.loaded = loaded
+	return nil
+}
+
+// Match reports whether the username matches an entry of the current
+// list, and returns the first entry it matches.
+func (b *Blacklist) Match(username string) (entry string, ok bool) {
+	return b.matcher.Load().Match(username)
+}
+
+// validate is the integrity Validator of the Blacklist.
+func (b *Blacklist) validate(s string) (bool, error) {
+	return b.matcher.Load().validate(s)
+}
+
+// Watch checks the files of the source every interval and reloads the
+// Blacklist when one of them changed since the last successful load,
+// until ctx is done. Reload errors are passed to onError, which may be
+// nil, and leave the current entries in use; the reload is retried
+// every interval until it succeeds. Watch returns the context's error, or an error at
+// once if the source reads no files.
+func (b *Blacklist) Watch(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if len(b.files) == 0 {
+		return errors.New("unamex: blacklist source has no files to watch")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		b.mu.Lock()
+		changed := !slices.Equal(stat(b.files), b.loaded)
+		b.mu.Unlock()
+		if !changed {
+			continue
+		}
+		if err := b.Reload(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// fileState identifies a version of a file; the zero value stands
+// for a missing file.
+type fileState struct {
+	mod  int64
+	size int64
+}
+
+func stat(files []string) []fileState {
+	states := make([]fileState, len(files))
+	for i, name := range files {
+		if fi, err := os.Stat(name); err == nil {
+			states[i] = fileState{mod: fi.ModTime().UnixNano(), size: fi.Size()}
+		}
+	}
+	return states
+}
+
+// readWordFile reads a newline-delimited word list, skipping blank
+// lines and '#' comments.
+func readWordFile(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readWords(f)
+}
+
+// readWords reads a word list in the format of readWordFile from r.
+func readWords(r io.Reader) ([]string, error) {
+	var words []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, sc.Err()
+}
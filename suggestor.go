@@ -0,0 +1,98 @@
+package unamex
+
+import "slices"
+
+// SuggestorNames returns the names of the default suggestors, in the
+// order they are registered, such as "SetSuffixRandomDigit" and
+// "VanishVowel". Suggestors added as Suggestor functions are named
+// "custom".
+func SuggestorNames() []string {
+	list := defaultSuggestors()
+	names := make([]string, len(list))
+	for i, s := range list {
+		names[i] = s.name
+	}
+	return names
+}
+
+// RegisterSuggestor adds a named suggestor to the Identity, tried with
+// the given weight relative to the others, whose default is 1. It
+// replaces a suggestor of the same name, such as one of the defaults,
+// in place and enables it.
+//
+// Example usage:
+//
+//	u := New("moree").RegisterSuggestor("HQ", 2, func(s string) string {
+//		return s + "hq"
+//	})
+func (u *Identity) RegisterSuggestor(name string, weight int, f Suggestor) *Identity {
+	s := fromSuggestors([]Suggestor{f})[0]
+	s.name, s.weight = name, max(weight, 0)
+
+	if i := slices.IndexFunc(u.suggestor, func(s suggestor) bool { return s.name == name }); i >= 0 {
+		u.suggestor[i] = s
+		return u
+	}
+	u.suggestor = append(u.suggestor, s)
+	return u
+}
+
+// WithSuggestorWeight sets the weight of the suggestors named name.
+// Suggest tries the suggestors in a random order in which each one
+// comes next with a probability proportional to its weight, so a
+// suggestor of weight 2 tends to be tried before one of weight 1.
+// A weight of zero or less never tries the suggestor.
+//
+// Example usage:
+//
+//	u := New("moree").
+//		WithSuggestorWeight("SetSuffixRandomDigit", 2).
+//		WithSuggestorWeight("VowelTransform", 1)
+func (u *Identity) WithSuggestorWeight(name string, weight int) *Identity {
+	for i := range u.suggestor {
+		if u.suggestor[i].name == name {
+			u.suggestor[i].weight = max(weight, 0)
+		}
+	}
+	return u
+}
+
+// DisableSuggestors stops the suggestors with the given names from
+// being tried, keeping their weight for EnableSuggestors. Unknown
+// names are ignored.
+//
+// Example usage:
+//
+//	u := New("moree").DisableSuggestors("VanishVowel", "SwapTwoChars")
+func (u *Identity) DisableSuggestors(names ...string) *Identity {
+	return u.toggleSuggestors(names, true)
+}
+
+// EnableSuggestors enables the suggestors with the given names again,
+// with the weight they had. Unknown names are ignored.
+func (u *Identity) EnableSuggestors(names ...string) *Identity {
+	return u.toggleSuggestors(names, false)
+}
+
+func (u *Identity) toggleSuggestors(names []string, off bool) *Identity {
+	for i := range u.suggestor {
+		if slices.Contains(names, u.suggestor[i].name) {
+			u.suggestor[i].off = off
+		}
+	}
+	return u
+}
+
+// inactive reports whether s is left out of the pool of an Engine.
+func (s suggestor) inactive() bool {
+	return s.off || s.weight <= 0
+}
+
+// activeSuggestors returns the suggestors of list that are tried,
+// which is list itself when they all are.
+func activeSuggestors(list []suggestor) []suggestor {
+	if !slices.ContainsFunc(list, suggestor.inactive) {
+		return list
+	}
+	return slices.DeleteFunc(slices.Clone(list), suggestor.inactive)
+}
@@ -0,0 +1,167 @@
+package unamex
+
+import (
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonScripts are looked up first by scriptOf, before the full
+// unicode.Scripts table.
+var commonScripts = []string{
+	"Latin", "Greek", "Cyrillic", "Arabic", "Hebrew", "Han", "Hiragana",
+	"Katakana", "Hangul", "Devanagari", "Thai", "Armenian", "Georgian",
+}
+
+// scriptGroups lists the scripts that are written together and are not
+// considered mixed, following the "highly restrictive" level of
+// Unicode Technical Standard #39.
+var scriptGroups = [][]string{
+	{"Han", "Hiragana", "Katakana"},
+	{"Han", "Hangul"},
+	{"Han", "Bopomofo"},
+}
+
+// isASCII reports whether s contains ASCII characters only.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isExtend reports whether r extends the preceding character into a
+// single user-perceived character: a combining mark, a variation
+// selector or a zero width joiner.
+func isExtend(r rune) bool {
+	return unicode.Is(unicode.M, r) || r == '\u200d' ||
+		unicode.Is(unicode.Variation_Selector, r)
+}
+
+// graphemeLen returns the number of user-perceived characters in s,
+// counting a base character and the marks that extend it as one.
+// Characters joined by a zero width joiner count once as well.
+// This approximates the extended grapheme clusters of Unicode
+// Standard Annex #29 for the letters and digits usernames are made of.
+func graphemeLen(s string) int {
+	if isASCII(s) {
+		return len(s)
+	}
+	n := 0
+	joined := true
+	for _, r := range s {
+		switch {
+		case r == '\u200d':
+			joined = true
+		case isExtend(r):
+		case joined && n > 0:
+			joined = false
+		default:
+			joined = false
+			n++
+		}
+	}
+	return n
+}
+
+// scriptOf returns the name of the Unicode script of r, such as
+// "Latin", or "" when r belongs to no script table.
+func scriptOf(r rune) string {
+	for _, name := range commonScripts {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// isMixedScript reports whether the set of scripts is mixed: more than
+// one script, unless they are all part of one of scriptGroups.
+func isMixedScript(set map[string]bool) bool {
+	if len(set) <= 1 {
+		return false
+	}
+group:
+	for _, g := range scriptGroups {
+		for name := range set {
+			if !slices.Contains(g, name) {
+				continue group
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// validateFormatUnicode is validateFormat for Policy.Unicode: letters
+// and digits are those of the Unicode categories L and Nd, combining
+// marks may follow a letter, and the scripts of the letters are
+// checked against Policy.Scripts and Policy.AllowMixedScripts.
+// The leading and trailing separators have already been checked.
+func (p Policy) validateFormatUnicode(input string) (bool, error) {
+	var countSeparators, countDigit, countChars int
+	var previousSeparator, previousLetter bool
+	scripts := make(map[string]bool)
+
+	for i, c := range input {
+		if c < utf8.RuneSelf && p.isSeparator(byte(c)) {
+			countSeparators++
+			if p.MaxSeparators >= 0 && countSeparators > p.MaxSeparators {
+				return false, &ValidationError{
+					Rule: RuleFormat, Code: CodeTooManySeps, Pos: i, Char: c,
+					Max: p.MaxSeparators,
+				}
+			}
+			if previousSeparator && !p.AllowConsecutiveSeparators {
+				return false, &ValidationError{
+					Rule: RuleFormat, Code: CodeConsecutiveSeps, Pos: i, Char: c,
+				}
+			}
+			previousSeparator, previousLetter = true, false
+			continue
+		}
+		previousSeparator = false
+
+		switch {
+		case unicode.IsLetter(c):
+			script := scriptOf(c)
+			if len(p.Scripts) > 0 && !slices.Contains(p.Scripts, script) {
+				return false, &ValidationError{
+					Rule: RuleFormat, Code: CodeScriptNotAllowed, Pos: i, Char: c,
+					Script: script,
+				}
+			}
+			scripts[script] = true
+			if !p.AllowMixedScripts && isMixedScript(scripts) {
+				return false, &ValidationError{
+					Rule: RuleFormat, Code: CodeMixedScripts, Pos: i, Char: c,
+					Script: script,
+				}
+			}
+			previousLetter = true
+			countChars++
+		case unicode.IsDigit(c):
+			previousLetter = false
+			countDigit++
+			countChars++
+		case previousLetter && isExtend(c):
+		default:
+			return false, &ValidationError{
+				Rule: RuleFormat, Code: CodeBadChar, Pos: i, Char: c,
+			}
+		}
+	}
+
+	if !p.AllowDigitsOnly && countDigit == countChars {
+		return false, &ValidationError{Rule: RuleFormat, Code: CodeDigitsOnly, Pos: -1}
+	}
+
+	return true, nil
+}
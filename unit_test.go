@@ -1,12 +1,25 @@
 package unamex
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf8"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 var mockValidator = func(username string) (bool, error) {
@@ -150,16 +163,36 @@ func TestValidate(t *testing.T) {
 	}
 	u := New()
 
-	t.Run("AppendExtraValidator", func(t *testing.T) {
+	t.Run("PerCallValidator", func(t *testing.T) {
 		currentLengthValidatorBefore := len(u.validator)
 
-		u.On(username).Validate(mockValidator)
-		addOneValidator := 1
+		for i := 0; i < 3; i++ {
+			u.On(username).Validate(mockValidator)
+		}
 		currentLengthValidatorAfter := len(u.validator)
 
 		require.Equal(t,
-			currentLengthValidatorBefore+addOneValidator,
+			currentLengthValidatorBefore,
 			currentLengthValidatorAfter)
+
+		// A one-off check does not leak into the next call
+		require.Error(t, u.On("moree").Validate(withUsernameAvailablilityCheck))
+		require.NoError(t, u.On("moree").Validate())
+	})
+
+	t.Run("AddValidator", func(t *testing.T) {
+		u := New()
+		currentLengthValidatorBefore := len(u.validator)
+
+		u.AddValidator(withUsernameAvailablilityCheck)
+		addOneValidator := 1
+
+		require.Len(t, u.validator, currentLengthValidatorBefore+addOneValidator)
+		require.Error(t, u.On("moree").Validate())
+		require.Error(t, u.On("moree").Validate())
+		for _, v := range u.Suggest(100) {
+			require.NotEqual(t, "morree", v)
+		}
 	})
 
 	t.Run("CheckDefaultRules", func(t *testing.T) {
@@ -181,28 +214,41 @@ func TestValidate(t *testing.T) {
 		require.Len(t, u.validator, zeroLength)
 		// Set a username
 		u.On("moree")
-		// Set a mock suggested username
-		u.isValid("moree")
-		// Expected validator length is > 0 with default validators
-		require.Greater(t, len(u.validator), zeroLength)
+		// The default validators apply without touching the Identity
+		require.False(t, u.engine().isValid(context.Background(), "moree", "moree"))
+		require.False(t, u.engine().isValid(context.Background(), "moree", "abc"))
+		require.True(t, u.engine().isValid(context.Background(), "moree", "morre"))
+		require.Len(t, u.validator, zeroLength)
 	})
 
 }
 
 func TestSuggest(t *testing.T) {
-	t.Run("AppendExtraSuggestor", func(t *testing.T) {
+	t.Run("PerCallSuggestor", func(t *testing.T) {
 		u := New()
 		suggestors := []Suggestor{mockSuggestor, mockSuggestor}
 		currentLengthSuggestors := len(u.suggestor)
-		addedSuggestorLength := len(suggestors)
 		u.Suggest(5, suggestors...)
 
-		require.Equal(t,
-			currentLengthSuggestors+addedSuggestorLength, len(u.suggestor))
+		require.Equal(t, currentLengthSuggestors, len(u.suggestor))
+
+		// Only the one-off suggestor can produce this suggestion
+		onlyMock := New("moree").WithSuggestor()
+		require.Equal(t, []string{"suggestedUsername"}, onlyMock.Suggest(5, mockSuggestor))
+		require.Empty(t, onlyMock.Suggest(5))
+	})
+	t.Run("AddSuggestor", func(t *testing.T) {
+		u := New("moree").WithSuggestor()
+		u.AddSuggestor(mockSuggestor)
+		addedSuggestorLength := 1
+
+		require.Len(t, u.suggestor, addedSuggestorLength)
+		require.Equal(t, []string{"suggestedUsername"}, u.Suggest(5))
+		require.Equal(t, []string{"suggestedUsername"}, u.Suggest(5))
 	})
 	t.Run("CheckUsernameVariants", func(t *testing.T) {
 		u := New("moree")
-		u.Validate(withUsernameAvailablilityCheck)
+		u.AddValidator(withUsernameAvailablilityCheck)
 		suggestions := u.Suggest(100)
 		for _, v := range suggestions {
 			if v != "" {
@@ -453,3 +499,2380 @@ func TestHelpers(t *testing.T) {
 	})
 
 }
+
+func TestValidationError(t *testing.T) {
+	t.Parallel()
+
+	var errTestCases = []struct {
+		username string
+		rule     string
+		code     Code
+		sentinel error
+		pos      int
+		char     rune
+	}{
+		{username: "", rule: RuleRange, code: CodeEmpty, sentinel: ErrEmpty, pos: -1},
+		{username: "sar", rule: RuleRange, code: CodeTooShort, sentinel: ErrTooShort, pos: -1},
+		{username: strings.Repeat("a", 31), rule: RuleRange, code: CodeTooLong, sentinel: ErrTooLong, pos: -1},
+		{username: "sarah!", rule: RuleFormat, code: CodeBadChar, sentinel: ErrBadChar, pos: 5, char: '!'},
+		{username: "sarahé", rule: RuleFormat, code: CodeBadChar, sentinel: ErrBadChar, pos: 5, char: 'é'},
+		{username: ".sarah", rule: RuleFormat, code: CodeLeadingSep, sentinel: ErrLeadingSep, pos: 0, char: '.'},
+		{username: "sarah.", rule: RuleFormat, code: CodeTrailingSep, sentinel: ErrTrailingSep, pos: 5, char: '.'},
+		{username: "sarah..adams", rule: RuleFormat, code: CodeTooManySeps, sentinel: ErrTooManySeps, pos: 6, char: '.'},
+		{username: "123456", rule: RuleFormat, code: CodeDigitsOnly, sentinel: ErrDigitsOnly, pos: -1},
+		{username: "Admin", rule: RuleIntegrity, code: CodeBlacklisted, sentinel: ErrBlacklisted, pos: -1},
+	}
+
+	for _, v := range errTestCases {
+		err := New(v.username).Validate()
+		require.ErrorIs(t, err, v.sentinel, v.username)
+
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, v.rule, ve.Rule, v.username)
+		require.Equal(t, v.code, ve.Code, v.username)
+		require.Equal(t, v.pos, ve.Pos, v.username)
+		require.Equal(t, v.char, ve.Char, v.username)
+		require.NotEmpty(t, ve.Error())
+	}
+
+	t.Run("Limits", func(t *testing.T) {
+		_, err := DefaultPolicy().validateRange("sar")
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, 5, ve.Min)
+		require.Equal(t, 30, ve.Max)
+		require.Equal(t, "username must be between 5 and 30 characters", ve.Error())
+	})
+
+	t.Run("CodeString", func(t *testing.T) {
+		require.Equal(t, "TooShort", CodeTooShort.String())
+		require.Equal(t, "Blacklisted", CodeBlacklisted.String())
+		require.Equal(t, "Code(200)", Code(200).String())
+	})
+
+	t.Run("UnknownCode", func(t *testing.T) {
+		ve := &ValidationError{Code: Code(200)}
+		require.Equal(t, ErrInvalid.Error(), ve.Error())
+		require.Nil(t, ve.Unwrap())
+
+		_, err := DefaultPolicy().validateFormat("")
+		require.ErrorIs(t, err, ErrEmpty)
+	})
+
+	t.Run("ScriptMessage", func(t *testing.T) {
+		ve := &ValidationError{Code: CodeScriptNotAllowed, Pos: 1, Char: 'ж', Script: "Cyrillic"}
+		require.Equal(t, "username contains 'ж' at position 1, "+
+			"letters of the Cyrillic script are not allowed", ve.Error())
+	})
+}
+
+func TestValidateAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("CollectsEveryFailure", func(t *testing.T) {
+		err := New("ad!").ValidateAll()
+		require.ErrorIs(t, err, ErrTooShort)
+		require.ErrorIs(t, err, ErrBadChar)
+
+		joined, ok := err.(interface{ Unwrap() []error })
+		require.True(t, ok)
+		require.Len(t, joined.Unwrap(), 2)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		require.NoError(t, New("sarah.adams").ValidateAll())
+	})
+
+	t.Run("PerCallValidators", func(t *testing.T) {
+		u := New("sarah.adams")
+		before := len(u.validator)
+		err := u.ValidateAll(func(string) (bool, error) { return false, nil })
+		require.ErrorIs(t, err, ErrInvalid)
+		require.Len(t, u.validator, before)
+	})
+
+	t.Run("FailFastStillFirst", func(t *testing.T) {
+		err := New("ad!").Validate()
+		require.ErrorIs(t, err, ErrTooShort)
+		require.NotErrorIs(t, err, ErrBadChar)
+	})
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	gamerTag := DefaultPolicy()
+	gamerTag.MinLength, gamerTag.MaxLength = 3, 15
+	gamerTag.Separators = "_-"
+	gamerTag.MaxSeparators = 2
+
+	var policyTestCases = []struct {
+		policy   Policy
+		username string
+		code     Code
+	}{
+		// Valid Cases
+		{policy: gamerTag, username: "abc"},
+		{policy: gamerTag, username: "ab_c-d"},
+		// Break policy cases
+		{policy: gamerTag, username: "ab", code: CodeTooShort},
+		{policy: gamerTag, username: "abcdefghijklmnop", code: CodeTooLong},
+		{policy: gamerTag, username: "a.bc", code: CodeBadChar},
+		{policy: gamerTag, username: "a_b-c_d", code: CodeTooManySeps},
+		{policy: gamerTag, username: "ab__c", code: CodeConsecutiveSeps},
+		{policy: gamerTag, username: "_abc", code: CodeLeadingSep},
+		{policy: gamerTag, username: "abc-", code: CodeTrailingSep},
+		{policy: gamerTag, username: "1_23", code: CodeDigitsOnly},
+		{policy: Policy{MinLength: 1, MaxLength: 64, MaxSeparators: -1,
+			Separators: ".", AllowDigitsOnly: true}, username: "1.2.3.4.5"},
+		{policy: Policy{MinLength: 1, MaxLength: 64, Separators: "-",
+			MaxSeparators: -1, AllowConsecutiveSeparators: true,
+			AllowLeadingSeparator: true, AllowTrailingSeparator: true},
+			username: "--enterprise--handle--"},
+	}
+
+	for _, v := range policyTestCases {
+		err := NewWithPolicy(v.policy, v.username).Validate()
+		if v.code == 0 {
+			require.NoError(t, err, v.username)
+			continue
+		}
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve, v.username)
+		require.Equal(t, v.code, ve.Code, v.username)
+	}
+
+	t.Run("DefaultPreservesRules", func(t *testing.T) {
+		u := NewWithPolicy(DefaultPolicy(), "sarah.adams")
+		require.NoError(t, u.Validate())
+		require.Error(t, u.On("sarah..adams").Validate())
+		require.Equal(t, DefaultPolicy(), New().policy)
+	})
+
+	t.Run("SuggestorsUsePolicySeparator", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Separators = "_"
+		u := NewWithPolicy(p, "moree")
+		for _, v := range u.Suggest(100) {
+			require.NotContains(t, v, ".")
+			require.NoError(t, u.On(v).Validate())
+			u.On("moree")
+		}
+	})
+
+	t.Run("NoSeparators", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Separators = ""
+		require.Equal(t, byte(separator), p.separator())
+	})
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	words := "# partner names\nacme\n\nglobex\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "extra.txt"), []byte(words), 0o600))
+
+	yamlDoc := `length:
+  min: 3
+  max: 15
+characters:
+  separators: "_-"
+  max_separators: 2
+blacklist:
+  builtin: true
+  words: [staff]
+  files: [extra.txt]
+reserved: [acmepay]
+suggestions:
+  count: 5
+  separator: "_"
+`
+	yamlPath := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(yamlDoc), 0o600))
+
+	t.Run("YAMLFile", func(t *testing.T) {
+		cfg, err := LoadConfigFile(yamlPath)
+		require.NoError(t, err)
+		require.Equal(t, 5, cfg.Suggestions.Count)
+		require.Equal(t, 5, cfg.SuggestionCount())
+
+		u := cfg.Identity("gamer_tag")
+		require.NoError(t, u.Validate())
+		for _, name := range []string{"admin", "staff", "acme", "Globex", "acmepay"} {
+			require.ErrorIs(t, u.On(name).Validate(), ErrBlacklisted, name)
+		}
+		require.ErrorIs(t, u.On("ab").Validate(), ErrTooShort)
+		for _, v := range u.On("gamer").Suggest(100) {
+			require.NotContains(t, v, ".")
+		}
+	})
+
+	t.Run("JSONDefaults", func(t *testing.T) {
+		cfg, err := LoadConfig(strings.NewReader(`{}`), FormatJSON)
+		require.NoError(t, err)
+		require.Equal(t, DefaultPolicy(), cfg.Policy())
+		require.Equal(t, 10, cfg.SuggestionCount())
+	})
+
+	t.Run("JSONWithoutBuiltin", func(t *testing.T) {
+		doc := `{"blacklist": {"builtin": false, "words": ["sarah"]}}`
+		cfg, err := LoadConfig(strings.NewReader(doc), FormatJSON)
+		require.NoError(t, err)
+		u := cfg.Identity("admin")
+		require.NoError(t, u.Validate())
+		require.ErrorIs(t, u.On("Sarah").Validate(), ErrBlacklisted)
+	})
+
+	var configErrorCases = []struct {
+		name   string
+		doc    string
+		format ConfigFormat
+		path   string
+		line   int
+		column int
+	}{
+		{name: "JSONSemantic", format: FormatJSON, path: "length.max", line: 3, column: 5,
+			doc: "{\n  \"length\": {\n    \"max\": 2\n  }\n}"},
+		{name: "JSONIndexed", format: FormatJSON, path: "reserved[1]", line: 1, column: 23,
+			doc: `{"reserved": ["acme", " "]}`},
+		{name: "JSONType", format: FormatJSON, path: "length.min", line: 1, column: 13,
+			doc: `{"length": {"min": "3"}}`},
+		{name: "JSONSyntax", format: FormatJSON, line: 2, column: 13,
+			doc: "{\n  \"length\": }"},
+		{name: "JSONTrailingDocument", format: FormatJSON, line: 1, column: 4,
+			doc: `{} {"length": {"min": 3}}`},
+		{name: "JSONTrailingGarbage", format: FormatJSON, line: 2, column: 1,
+			doc: "{\"length\": {\"min\": 3}}\ngarbage"},
+		{name: "YAMLSemantic", format: FormatYAML, path: "characters.separators", line: 4, column: 3,
+			doc: "length:\n  min: 3\ncharacters:\n  separators: \"_a\"\n"},
+		{name: "YAMLIndexed", format: FormatYAML, path: "blacklist.files[0]", line: 3, column: 7,
+			doc: "blacklist:\n  files:\n    - missing.txt\n"},
+		{name: "YAMLUnknownNestedField", format: FormatYAML, path: "length.mx", line: 3, column: 3,
+			doc: "length:\n  min: 3\n  mx: 4\n"},
+		{name: "YAMLType", format: FormatYAML, path: "length.min", line: 2, column: 8,
+			doc: "length:\n  min: abc\n"},
+		{name: "YAMLIndexedType", format: FormatYAML, path: "suggestions.disabled[1]", line: 4, column: 7,
+			doc: "suggestions:\n  disabled:\n    - VanishVowel\n    - [x]\n"},
+		{name: "YAMLSyntax", format: FormatYAML, line: 2,
+			doc: "length:\n  min: 3\n max: [\n"},
+	}
+
+	for _, v := range configErrorCases {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := LoadConfig(strings.NewReader(v.doc), v.format)
+			var ce *ConfigError
+			require.ErrorAs(t, err, &ce)
+			require.Equal(t, v.path, ce.Path)
+			require.Equal(t, v.line, ce.Line)
+			require.Equal(t, v.column, ce.Column)
+			require.NotEmpty(t, ce.Error())
+		})
+	}
+
+	t.Run("YAMLUnknownField", func(t *testing.T) {
+		_, err := LoadConfig(strings.NewReader("lenght:\n  min: 3\n"), FormatYAML)
+		require.ErrorContains(t, err, "line 1")
+	})
+
+	t.Run("UnknownExtension", func(t *testing.T) {
+		_, err := LoadConfigFile(filepath.Join(dir, "policy.toml"))
+		require.Error(t, err)
+	})
+
+	var configPathCases = []struct {
+		name string
+		doc  string
+		path string
+	}{
+		{name: "MinLength", path: "length.min", doc: `{"length": {"min": 0}}`},
+		{name: "MaxSeparators", path: "characters.max_separators",
+			doc: `{"characters": {"max_separators": -2}}`},
+		{name: "EmptyWord", path: "blacklist.words[0]", doc: `{"blacklist": {"words": [" "]}}`},
+		{name: "NegativeCount", path: "suggestions.count", doc: `{"suggestions": {"count": -1}}`},
+		{name: "Separator", path: "suggestions.separator", doc: `{"suggestions": {"separator": "x"}}`},
+		{name: "BadWord", path: "blacklist.words[0]", doc: `{"blacklist": {"words": ["regex:("]}}`},
+		{name: "EmptyAllowPattern", path: "allowlist[0].pattern",
+			doc: `{"allowlist": [{"pattern": " "}]}`},
+		{name: "EmptyProfanityWord", path: "profanity.words[0]", doc: `{"profanity": {"words": [" "]}}`},
+		{name: "EmptyException", path: "profanity.exceptions[0]",
+			doc: `{"profanity": {"exceptions": [" "]}}`},
+		{name: "EmptyCategoryWord", path: "categories.x[0]", doc: `{"categories": {"x": [" "]}}`},
+		{name: "UnknownWeight", path: "suggestions.weights.nope",
+			doc: `{"suggestions": {"weights": {"nope": 1}}}`},
+	}
+
+	for _, v := range configPathCases {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := LoadConfig(strings.NewReader(v.doc), FormatJSON)
+			var ce *ConfigError
+			require.ErrorAs(t, err, &ce)
+			require.Equal(t, v.path, ce.Path)
+			require.Equal(t, 1, ce.Line)
+		})
+	}
+
+	t.Run("Files", func(t *testing.T) {
+		jsonPath := filepath.Join(dir, "policy.json")
+		require.NoError(t, os.WriteFile(jsonPath, []byte(`{"reserved": ["acmepay"]}`), 0o600))
+		cfg, err := LoadConfigFile(jsonPath)
+		require.NoError(t, err)
+		require.ErrorIs(t, cfg.Identity("acmepay").Validate(), ErrBlacklisted)
+
+		_, err = LoadConfigFile(filepath.Join(dir, "missing.yaml"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = LoadConfig(strings.NewReader(`{"blacklist": {"files": ["missing.txt"]}}`), FormatJSON)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		errRead := errors.New("read failed")
+		_, err := LoadConfig(iotest.ErrReader(errRead), FormatJSON)
+		require.ErrorIs(t, err, errRead)
+
+		_, err = LoadConfig(strings.NewReader("{}"), ConfigFormat(9))
+		require.ErrorContains(t, err, "unknown format")
+
+		_, err = LoadConfig(strings.NewReader("[1]"), FormatJSON)
+		require.Error(t, err)
+	})
+
+	t.Run("Locators", func(t *testing.T) {
+		for _, doc := range []string{`{"length": {"min": 3`, `{"length": [1, `, `{"length" 3}`, `{`} {
+			line, col := jsonLocator([]byte(doc))("length.max")
+			require.Zero(t, line, doc)
+			require.Zero(t, col, doc)
+		}
+
+		var root yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte("length:\n  min: 3\nreserved: [acme]\n"), &root))
+		locate := yamlLocator(&root)
+		line, col := locate("length.max")
+		require.Equal(t, []int{1, 1}, []int{line, col})
+		line, col = locate("reserved[3]")
+		require.Equal(t, []int{3, 1}, []int{line, col})
+		line, col = locate("length.min[0]")
+		require.Equal(t, []int{2, 3}, []int{line, col})
+
+		ce := yamlConfigError(&yaml.Node{}, errors.New("boom"))
+		require.Equal(t, &ConfigError{Err: errors.New("boom")}, ce)
+
+		line, col = lineColumn([]byte("a\nb"), 10)
+		require.Equal(t, []int{2, 2}, []int{line, col})
+	})
+
+	t.Run("BadFile", func(t *testing.T) {
+		badPath := filepath.Join(dir, "bad.txt")
+		require.NoError(t, os.WriteFile(badPath, []byte("regex:(\n"), 0o600))
+		doc := fmt.Sprintf(`{"blacklist": {"files": [%q]}}`, badPath)
+		_, err := LoadConfig(strings.NewReader(doc), FormatJSON)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "blacklist.files[0]", ce.Path)
+	})
+
+	t.Run("YAMLNested", func(t *testing.T) {
+		doc := "allowlist:\n  - pattern: support\n    rules: {a: b}\n"
+		_, err := LoadConfig(strings.NewReader(doc), FormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "allowlist[0].rules", ce.Path)
+		require.Equal(t, 3, ce.Line)
+	})
+}
+
+func TestEngine(t *testing.T) {
+	t.Parallel()
+
+	u := New().WithValidator(append(defaultValidator(), withUsernameAvailablilityCheck)...)
+	engine := u.Engine()
+
+	t.Run("Snapshot", func(t *testing.T) {
+		u := New()
+		engine := u.Engine()
+		u.WithValidator(func(string) (bool, error) { return false, nil })
+		require.NoError(t, engine.Validate(context.Background(), "sarah.adams"))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		ctx := context.Background()
+		require.NoError(t, engine.Validate(ctx, "sarah.adams"))
+		require.ErrorIs(t, engine.Validate(ctx, "ad!"), ErrTooShort)
+		require.EqualError(t, engine.Validate(ctx, "moree"), "this username is unavailable")
+
+		err := engine.ValidateAll(ctx, "ad!")
+		require.ErrorIs(t, err, ErrTooShort)
+		require.ErrorIs(t, err, ErrBadChar)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, engine.Validate(ctx, "sarah.adams"), context.Canceled)
+		require.ErrorIs(t, engine.ValidateAll(ctx, "sarah.adams"), context.Canceled)
+		suggestions, err := engine.Suggest(ctx, "moree", 5)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Empty(t, suggestions)
+	})
+
+	t.Run("EmptyName", func(t *testing.T) {
+		ctx := context.Background()
+		suggestions, err := engine.Suggest(ctx, "", 5)
+		require.NoError(t, err)
+		require.Empty(t, suggestions)
+		detailed, err := engine.SuggestDetailed(ctx, "", 5)
+		require.NoError(t, err)
+		require.Empty(t, detailed)
+		available, err := engine.SuggestAvailable(ctx, "", 1, nil)
+		require.NoError(t, err)
+		require.Empty(t, available)
+
+		for _, f := range defaultSuggestors() {
+			require.NotPanics(t, func() { f.fn(globalRand{}, "") }, f.name)
+		}
+		require.Equal(t, "", RepeatSubfix(""))
+		require.Equal(t, "", RepeatPrefix(""))
+		require.Equal(t, "", RepeatSuffix(""))
+		require.Equal(t, "", SetPostInitialSep("", '.'))
+		require.Equal(t, "", SetPenultimateSep("", '.'))
+	})
+
+	t.Run("NegativeCount", func(t *testing.T) {
+		suggestions, err := engine.Suggest(context.Background(), "moree", -1)
+		require.NoError(t, err)
+		require.Empty(t, suggestions)
+		detailed, err := engine.SuggestDetailed(context.Background(), "moree", -1)
+		require.NoError(t, err)
+		require.Empty(t, detailed)
+		require.Empty(t, New("moree").Suggest(-3))
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		// Run with -race to prove the Engine shares no mutable state.
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ctx := context.Background()
+				for n := 0; n < 100; n++ {
+					name := "sarah" + strconv.Itoa(i)
+					// require would stop this goroutine instead of the test.
+					assert.NoError(t, engine.Validate(ctx, name))
+					suggestions, err := engine.Suggest(ctx, name, 5)
+					assert.NoError(t, err)
+					for _, v := range suggestions {
+						assert.NotEqual(t, name, v)
+						assert.NoError(t, engine.Validate(ctx, v))
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	t.Run("ConcurrentIdentitySnapshots", func(t *testing.T) {
+		u := New()
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				engine := u.Engine()
+				_, _ = engine.Suggest(context.Background(), "moree", 16)
+			}()
+		}
+		wg.Wait()
+		require.Len(t, u.suggestor, len(defaultSuggestors()))
+	})
+
+	t.Run("CanceledByValidator", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		u := New("moree").
+			AddValidatorCtx(func(context.Context, string) error { cancel(); return nil }).
+			AddValidatorCtx(func(context.Context, string) error { return nil })
+		suggestions, err := u.SuggestContext(ctx, 5)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Empty(t, suggestions)
+	})
+}
+
+func TestValidatorCtx(t *testing.T) {
+	t.Parallel()
+
+	availability := func(ctx context.Context, username string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if ok, err := withUsernameAvailablilityCheck(username); !ok {
+			return err
+		}
+		return nil
+	}
+
+	t.Run("ValidateContext", func(t *testing.T) {
+		u := New("moree")
+		ctx := context.Background()
+		require.EqualError(t, u.ValidateContext(ctx, availability), "this username is unavailable")
+		// The per-call rule does not leak into the next call
+		require.NoError(t, u.ValidateContext(ctx))
+		require.Empty(t, u.validatorCtx)
+	})
+
+	t.Run("AddValidatorCtx", func(t *testing.T) {
+		u := New("moree").AddValidatorCtx(availability)
+		require.Error(t, u.Validate())
+		require.Error(t, u.ValidateAll())
+		require.NoError(t, u.On("sarah.adams").Validate())
+
+		suggestions, err := u.On("moree").SuggestContext(context.Background(), 100)
+		require.NoError(t, err)
+		for _, v := range suggestions {
+			require.NoError(t, availability(context.Background(), v))
+		}
+
+		u.WithValidator()
+		require.Empty(t, u.validatorCtx)
+	})
+
+	t.Run("ContextValidator", func(t *testing.T) {
+		ctx := context.Background()
+		require.NoError(t, ContextValidator(mockValidator)(ctx, "sarah"))
+		require.Error(t, ContextValidator(mockValidator)(ctx, ""))
+		require.ErrorIs(t, ContextValidator(func(string) (bool, error) {
+			return false, nil
+		})(ctx, "sarah"), ErrInvalid)
+	})
+
+	t.Run("CancellationBetweenRules", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int
+		first := func(context.Context, string) error {
+			calls++
+			cancel()
+			return nil
+		}
+		second := func(context.Context, string) error {
+			calls++
+			return nil
+		}
+		err := New("sarah.adams").ValidateContext(ctx, first, second)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("SuggestContextCanceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		suggestions, err := New("moree").SuggestContext(ctx, 5, mockSuggestor)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Empty(t, suggestions)
+	})
+}
+
+func TestSuggestAvailable(t *testing.T) {
+	t.Parallel()
+
+	taken := AvailabilityFunc(func(_ context.Context, s string) (bool, error) {
+		ok, _ := withUsernameAvailablilityCheck(s)
+		return ok, nil
+	})
+
+	t.Run("GuaranteesN", func(t *testing.T) {
+		ctx := context.Background()
+		u := New("moree")
+		for i := 0; i < 20; i++ {
+			suggestions, err := u.SuggestAvailable(ctx, 10, taken)
+			require.NoError(t, err)
+			require.Len(t, suggestions, 10)
+
+			seen := make(map[string]bool)
+			for _, v := range suggestions {
+				require.False(t, seen[v])
+				seen[v] = true
+				require.NotEqual(t, "moree", v)
+				require.NoError(t, u.engine().Validate(ctx, v))
+				ok, _ := withUsernameAvailablilityCheck(v)
+				require.True(t, ok, v)
+			}
+		}
+	})
+
+	t.Run("BudgetExhausted", func(t *testing.T) {
+		u := New("moree").WithSuggestor(mockSuggestor).WithMaxAttempts(5)
+		suggestions, err := u.SuggestAvailable(context.Background(), 2, nil)
+		require.ErrorIs(t, err, ErrSuggestionBudget)
+		require.ErrorContains(t, err, "found 1 of 2 suggestions in 5 attempts")
+		require.Equal(t, []string{"suggestedUsername"}, suggestions)
+
+		_, err = New("moree").WithSuggestor().SuggestAvailable(context.Background(), 1, nil)
+		require.ErrorIs(t, err, ErrSuggestionBudget)
+
+		u.WithMaxAttempts(-1)
+		require.Zero(t, u.maxAttempts)
+	})
+
+	t.Run("NothingAvailable", func(t *testing.T) {
+		none := AvailabilityFunc(func(context.Context, string) (bool, error) {
+			return false, nil
+		})
+		_, err := New("moree").WithMaxAttempts(50).SuggestAvailable(context.Background(), 1, none)
+		require.ErrorContains(t, err, "in 50 attempts")
+	})
+
+	t.Run("CheckerError", func(t *testing.T) {
+		errDown := errors.New("database is down")
+		down := AvailabilityFunc(func(context.Context, string) (bool, error) {
+			return false, errDown
+		})
+		_, err := New("moree").SuggestAvailable(context.Background(), 3, down)
+		require.ErrorIs(t, err, errDown)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := New("moree").SuggestAvailable(ctx, 3, taken)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// countingBatch is a batch-only checker that records its round trips
+type countingBatch struct {
+	*MemoryChecker
+	calls int
+}
+
+func (c *countingBatch) Taken(ctx context.Context, usernames []string) ([]string, error) {
+	c.calls++
+	return c.MemoryChecker.Taken(ctx, usernames)
+}
+
+func TestBatchAvailability(t *testing.T) {
+	t.Parallel()
+
+	t.Run("MemoryChecker", func(t *testing.T) {
+		ctx := context.Background()
+		m := NewMemoryChecker("morree", "moree1").Add("1moree")
+
+		ok, err := m.Available(ctx, "morree")
+		require.NoError(t, err)
+		require.False(t, ok)
+		ok, err = m.Available(ctx, "mooree")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		taken, err := m.Taken(ctx, []string{"mooree", "1moree", "morree"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1moree", "morree"}, taken)
+	})
+
+	t.Run("OneRoundTrip", func(t *testing.T) {
+		ctx := context.Background()
+		checker := &countingBatch{MemoryChecker: NewMemoryChecker("morree", "moreee")}
+		u := New("moree")
+
+		suggestions, err := u.SuggestAvailable(ctx, 3, BatchChecker(checker))
+		require.NoError(t, err)
+		require.Len(t, suggestions, 3)
+		require.Equal(t, 1, checker.calls)
+
+		taken, err := checker.MemoryChecker.Taken(ctx, suggestions)
+		require.NoError(t, err)
+		require.Empty(t, taken)
+	})
+
+	t.Run("MostTaken", func(t *testing.T) {
+		ctx := context.Background()
+		u := New("moree")
+		// Take every name the first round could produce, then ask again
+		first, err := u.SuggestAvailable(ctx, 5, NewMemoryChecker())
+		require.NoError(t, err)
+
+		checker := NewMemoryChecker(first...)
+		second, err := u.SuggestAvailable(ctx, 5, checker)
+		require.NoError(t, err)
+		require.Len(t, second, 5)
+		for _, v := range second {
+			require.NotContains(t, first, v)
+		}
+	})
+
+	t.Run("AdapterAvailable", func(t *testing.T) {
+		checker := BatchChecker(NewMemoryChecker("morree"))
+		ok, err := checker.Available(context.Background(), "morree")
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("BatchError", func(t *testing.T) {
+		errDown := errors.New("database is down")
+		down := BatchChecker(batchFunc(func(context.Context, []string) ([]string, error) {
+			return nil, errDown
+		}))
+		_, err := New("moree").SuggestAvailable(context.Background(), 3, down)
+		require.ErrorIs(t, err, errDown)
+	})
+}
+
+type batchFunc func(ctx context.Context, usernames []string) ([]string, error)
+
+func (f batchFunc) Taken(ctx context.Context, usernames []string) ([]string, error) {
+	return f(ctx, usernames)
+}
+
+// fixedRand always returns the same choice, clamped to n
+type fixedRand int
+
+func (f fixedRand) IntN(n int) int {
+	return min(int(f), n-1)
+}
+
+func TestRand(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SameSeedSameSuggestions", func(t *testing.T) {
+		a := New("john.smith").WithSeed(42)
+		b := New("john.smith").WithSeed(42)
+		for i := 0; i < 5; i++ {
+			require.Equal(t, a.Suggest(10), b.Suggest(10))
+		}
+
+		ctx := context.Background()
+		ea := New("moree").WithSeed(7).Engine()
+		eb := New("moree").WithSeed(7).Engine()
+		sa, err := ea.SuggestAvailable(ctx, "moree", 8, NewMemoryChecker("morree"))
+		require.NoError(t, err)
+		sb, err := eb.SuggestAvailable(ctx, "moree", 8, NewMemoryChecker("morree"))
+		require.NoError(t, err)
+		require.Equal(t, sa, sb)
+	})
+
+	t.Run("DifferentSeeds", func(t *testing.T) {
+		a := New("john.smith").WithSeed(1).Suggest(16)
+		b := New("john.smith").WithSeed(2).Suggest(16)
+		require.NotEqual(t, a, b)
+	})
+
+	t.Run("HelperVariants", func(t *testing.T) {
+		r := fixedRand(0)
+		require.Equal(t, "evry", VanishVowelRand(r, "every"))
+		require.Equal(t, "wurld", VowelTransformRand(r, "world", vowelSwap))
+		require.Equal(t, "woorld", RepeatVowelRand(r, "world"))
+		require.Equal(t, "user.0", SetPenultimateSepDigitRand(r, "user", '.'))
+		require.Equal(t, "0.user", SetPostInitialSepDigitRand(r, "user", '.'))
+		require.Equal(t, "user.0", SepWithRandomDigitRand(r, "user", '.', 10))
+		require.Equal(t, "uuser0", RepeatInitialAppendDigitRand(r, "user", 10))
+		require.Equal(t, "10user", PrefixRandomDigitRand(r, "user", 100))
+		require.Equal(t, "user0", SuffixRandomDigitRand(r, "user", 10))
+		require.Equal(t, "100user", SetPrefixRandomDigitRand(r, "user"))
+		require.Equal(t, "user0", SetSuffixRandomDigitRand(r, "user"))
+		require.Equal(t, "user.0", SetSepWithRandomDigitRand(r, "user"))
+
+		r = fixedRand(1)
+		require.Equal(t, "evry", VanishVowelRand(r, "every"))
+		require.Equal(t, "user.1", SetSepWithRandomDigitRand(r, "user"))
+		require.Equal(t, "2user", SetPrefixRandomDigitRand(fixedRand(2), "user"))
+
+		pcg := rand.New(rand.NewPCG(3, 3))
+		first := SetSuffixRandomDigitRand(pcg, "user")
+		pcg = rand.New(rand.NewPCG(3, 3))
+		require.Equal(t, first, SetSuffixRandomDigitRand(pcg, "user"))
+	})
+
+	t.Run("ConcurrentSeededEngine", func(t *testing.T) {
+		engine := New().WithSeed(9).Engine()
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for n := 0; n < 50; n++ {
+					_, _ = engine.Suggest(context.Background(), "moree", 16)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("NilRestoresGlobal", func(t *testing.T) {
+		u := New().WithSeed(1).WithRand(nil)
+		require.Nil(t, u.rnd)
+		require.IsType(t, globalRand{}, u.engine().rand("moree"))
+	})
+}
+
+func TestDeterministic(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+
+	t.Run("HashRandGolden", func(t *testing.T) {
+		// The hash stream is part of the contract: replicas on other
+		// versions must keep producing the same choices.
+		h := NewHashRand(key, "john.smith", "sso", 0)
+		var got []int
+		for i := 0; i < 6; i++ {
+			got = append(got, h.IntN(1000))
+		}
+		require.Equal(t, []int{741, 3, 717, 307, 762, 749}, got)
+		require.Panics(t, func() { h.IntN(0) })
+	})
+
+	t.Run("StableAcrossReplicas", func(t *testing.T) {
+		a := New("john.smith").WithDeterministic(key, "sso")
+		b := New("john.smith").WithDeterministic(key, "sso").Engine()
+		first := a.Suggest(16)
+		require.NotEmpty(t, first)
+		for i := 0; i < 5; i++ {
+			require.Equal(t, first, a.Suggest(16))
+			again, err := b.Suggest(context.Background(), "john.smith", 16)
+			require.NoError(t, err)
+			require.Equal(t, first, again)
+		}
+
+		checker := NewMemoryChecker("jjohn.smith5")
+		sa, err := a.SuggestAvailable(context.Background(), 5, checker)
+		require.NoError(t, err)
+		sb, err := b.SuggestAvailable(context.Background(), "john.smith", 5, checker)
+		require.NoError(t, err)
+		require.Equal(t, sa, sb)
+	})
+
+	t.Run("InputsMatter", func(t *testing.T) {
+		base := NewHashRand(key, "john.smith", "sso", 0).IntN(1 << 30)
+		require.NotEqual(t, base, NewHashRand(key, "jane.smith", "sso", 0).IntN(1<<30))
+		require.NotEqual(t, base, NewHashRand(key, "john.smith", "onboarding", 0).IntN(1<<30))
+		require.NotEqual(t, base, NewHashRand(key, "john.smith", "sso", 1).IntN(1<<30))
+		require.NotEqual(t, base, NewHashRand([]byte("other"), "john.smith", "sso", 0).IntN(1<<30))
+		require.NotEqual(t,
+			NewHashRand(key, "ab", "c", 0).IntN(1<<30),
+			NewHashRand(key, "a", "bc", 0).IntN(1<<30))
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		h := NewHashRand(key, "moree", "", 0)
+		for i := 0; i < 1000; i++ {
+			v := h.IntN(3)
+			require.GreaterOrEqual(t, v, 0)
+			require.Less(t, v, 3)
+		}
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		u := New("moree").WithDeterministic(key, "sso").WithDeterministic(nil, "")
+		require.Nil(t, u.hashKey)
+		require.IsType(t, globalRand{}, u.engine().rand("moree"))
+	})
+}
+
+func TestUnicode(t *testing.T) {
+	t.Parallel()
+
+	intl := DefaultPolicy()
+	intl.MinLength, intl.MaxLength = 3, 10
+	intl.Unicode = true
+
+	latinGreek := intl
+	latinGreek.Scripts = []string{"Latin", "Greek"}
+
+	mixed := intl
+	mixed.AllowMixedScripts = true
+
+	var unicodeTestCases = []struct {
+		policy   Policy
+		username string
+		code     Code
+		pos      int
+		script   string
+	}{
+		// Valid Cases
+		{policy: intl, username: "José"},
+		{policy: intl, username: "Jose\u0301"},
+		{policy: intl, username: "Ἀλέξανδρος"},
+		{policy: intl, username: "山田たろう"},
+		{policy: intl, username: "Иван.42"},
+		{policy: latinGreek, username: "Ζωή.42"},
+		{policy: latinGreek, username: "sofia"},
+		{policy: mixed, username: "p\u0430ypal"},
+		// Break policy cases
+		{policy: DefaultPolicy(), username: "josé.m", code: CodeBadChar, pos: 3},
+		{policy: intl, username: "Jó", code: CodeTooShort},
+		{policy: intl, username: "abc\U0001f600", code: CodeBadChar, pos: 3},
+		{policy: intl, username: "\u0301abc", code: CodeBadChar, pos: 0},
+		{policy: intl, username: "١٢٣", code: CodeDigitsOnly, pos: -1},
+		{policy: intl, username: "p\u0430ypal", code: CodeMixedScripts, pos: 1, script: "Cyrillic"},
+		{policy: latinGreek, username: "Иван", code: CodeScriptNotAllowed, script: "Cyrillic"},
+	}
+
+	for _, v := range unicodeTestCases {
+		err := NewWithPolicy(v.policy, v.username).Validate()
+		if v.code == 0 {
+			require.NoError(t, err, v.username)
+			continue
+		}
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve, v.username)
+		require.Equal(t, v.code, ve.Code, v.username)
+		if v.code != CodeTooShort {
+			require.Equal(t, v.pos, ve.Pos, v.username)
+		}
+		require.Equal(t, v.script, ve.Script, v.username)
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		err := NewWithPolicy(intl, "p\u0430ypal").Validate()
+		require.ErrorIs(t, err, ErrMixedScripts)
+		require.EqualError(t, err, "username contains '\u0430' at position 1, "+
+			"usernames cannot mix Cyrillic letters with letters of another script")
+		require.ErrorIs(t, NewWithPolicy(latinGreek, "Иван").Validate(),
+			ErrScriptNotAllowed)
+	})
+
+	t.Run("GraphemeLen", func(t *testing.T) {
+		require.Equal(t, 5, graphemeLen("moree"))
+		require.Equal(t, 4, graphemeLen("José"))
+		require.Equal(t, 4, graphemeLen("Jose\u0301"))
+		require.Equal(t, 3, graphemeLen("a\U0001f469\u200d\U0001f4bbb"))
+	})
+
+	t.Run("RuneSafeSuggestors", func(t *testing.T) {
+		for _, s := range []string{"Ζωή", "été", "山田たろう"} {
+			for _, f := range suggestorsFor('.') {
+				v := f.fn(NewHashRand(nil, s, "", 0), s)
+				require.True(t, utf8.ValidString(v), "%q -> %q", s, v)
+			}
+		}
+		require.Equal(t, "ζζωή", RepeatPrefix("ζωή"))
+		require.Equal(t, "ζωήή", RepeatSuffix("ζωή"))
+		require.Equal(t, "ζ.ωή", SetPostInitialSep("ζωή", '.'))
+		require.Equal(t, "ζω.ή", SetPenultimateSep("ζωή", '.'))
+		require.Equal(t, "ζήω", SwapTwoChars("ζωή"))
+		require.Equal(t, "ζωωή", RepeatSubfix("ζωή"))
+		require.Equal(t, "ζωή", VowelTransform("ζωή", vowelSwap))
+	})
+
+	t.Run("Suggest", func(t *testing.T) {
+		u := NewWithPolicy(intl, "Ζωή.μ").WithSeed(7)
+		suggestions := u.Suggest(16)
+		require.NotEmpty(t, suggestions)
+		for _, v := range suggestions {
+			require.True(t, utf8.ValidString(v), v)
+			require.NoError(t, u.On(v).Validate(), v)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		c, err := LoadConfig(strings.NewReader(
+			"characters:\n  unicode: true\n  scripts: [Latin, Greek]\n"), FormatYAML)
+		require.NoError(t, err)
+		require.True(t, c.Policy().Unicode)
+		require.Equal(t, []string{"Latin", "Greek"}, c.Policy().Scripts)
+
+		_, err = LoadConfig(strings.NewReader(
+			"characters:\n  unicode: true\n  scripts: [Latin, Klingon]\n"), FormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "characters.scripts[1]", ce.Path)
+		require.Equal(t, 3, ce.Line)
+
+		_, err = LoadConfig(strings.NewReader(
+			"characters:\n  scripts: [Latin]\n"), FormatYAML)
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "characters.scripts", ce.Path)
+	})
+
+	t.Run("Scripts", func(t *testing.T) {
+		require.Equal(t, "Latin", scriptOf('é'))
+		require.Equal(t, "Ethiopic", scriptOf('\u1200'))
+		require.Equal(t, "", scriptOf('\U0010ffff'))
+		require.Equal(t, "éa", SwapTwoChars("éa"))
+
+		p := intl
+		p.MaxSeparators = 3
+		_, err := p.validateFormatUnicode("Ζωή..μ")
+		require.ErrorIs(t, err, ErrConsecutiveSeps)
+	})
+}
+
+type protectedFunc func(ctx context.Context, skeleton string) (string, bool, error)
+
+func (f protectedFunc) LookupSkeleton(ctx context.Context, skeleton string) (string, bool, error) {
+	return f(ctx, skeleton)
+}
+
+func TestConfusable(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Skeleton", func(t *testing.T) {
+		var skeletonTestCases = []struct {
+			a, b string
+			same bool
+		}{
+			{"paypa1", "paypal", true},
+			{"PayPal", "paypal", true},
+			{"rnoree", "moree", true},
+			{"0racle", "oracle", true},
+			{"\u0430dmin", "admin", true},
+			{"\u0410DMIN", "admin", true},
+			{"ａｄｍｉｎ", "admin", true},
+			{"ad\u200bmin", "admin", true},
+			{"\u03bfracle", "oracle", true},
+			{"PaypaI", "paypal", true},
+			{"ADMlN", "admin", true},
+			{"AdMiN", "ADMIN", true},
+			{"pAyPa1", "PAYPAL", true},
+			{"\u0397ugo", "hugo", true},
+			{"\u039dina", "Nina", true},
+			{"\u0399vy", "lvy", true},
+			{"moree", "mores", false},
+			{"jos\u00e9", "jose", false},
+		}
+		for _, v := range skeletonTestCases {
+			require.Equal(t, v.same, Skeleton(v.a) == Skeleton(v.b), "%q %q", v.a, v.b)
+		}
+		require.Equal(t, "rnoree", Skeleton("Moree"))
+		require.Equal(t, Skeleton("jos\u00e9"), Skeleton("jose\u0301"))
+	})
+
+	t.Run("Validator", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Reserved = []string{"acmepay"}
+		v := p.ConfusableValidator(NewProtectedSet("moree", "support.team"))
+
+		var confusableTestCases = []struct {
+			username string
+			match    string
+		}{
+			{username: "0racle", match: "oracle"},
+			{username: "\u043eracle", match: "oracle"},
+			{username: "acrnepay", match: "acmepay"},
+			{username: "rnoree", match: "moree"},
+			{username: "supp0rt.tearn", match: "support.team"},
+			{username: "moree"},
+			{username: "MoReE"},
+			{username: "sarah.adams"},
+		}
+		for _, c := range confusableTestCases {
+			err := v(context.Background(), c.username)
+			if c.match == "" {
+				require.NoError(t, err, c.username)
+				continue
+			}
+			var ve *ValidationError
+			require.ErrorAs(t, err, &ve, c.username)
+			require.Equal(t, RuleConfusable, ve.Rule)
+			require.Equal(t, CodeConfusable, ve.Code)
+			require.Equal(t, c.match, ve.Match, c.username)
+			require.ErrorIs(t, err, ErrConfusable)
+		}
+		require.EqualError(t, v(context.Background(), "rnoree"),
+			`username looks like "moree", please choose a different one`)
+	})
+
+	t.Run("LookupError", func(t *testing.T) {
+		errStore := errors.New("store down")
+		v := DefaultPolicy().ConfusableValidator(protectedFunc(
+			func(context.Context, string) (string, bool, error) { return "", false, errStore }))
+		require.ErrorIs(t, v(context.Background(), "sarah.adams"), errStore)
+		require.NoError(t, DefaultPolicy().ConfusableValidator(nil)(context.Background(), "sarah.adams"))
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		staff := NewProtectedSet("moree1")
+		u := New("rnoree1").WithConfusables(staff)
+		require.ErrorIs(t, u.ValidateContext(context.Background()), ErrConfusable)
+
+		require.NoError(t, u.On("moree1").ValidateContext(context.Background()))
+
+		// Suggestions never return the protected name itself.
+		u.On("moree").AddSuggestor(func(s string) string { return s + "1" })
+		suggestions, err := u.SuggestContext(context.Background(), 17)
+		require.NoError(t, err)
+		for _, s := range suggestions {
+			require.NotEqual(t, Skeleton("moree1"), Skeleton(s), s)
+		}
+	})
+
+	t.Run("PatternEntries", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Reserved = []string{"prefix:acme"}
+		require.NoError(t, p.ConfusableValidator(nil)(context.Background(), "acrne"))
+	})
+}
+
+func TestNormalization(t *testing.T) {
+	t.Parallel()
+
+	all := DefaultPolicy()
+	all.Separators = "._-"
+	all.MaxSeparators = -1
+	all.Normalization = NormalizeAll
+
+	var normalizeTestCases = []struct {
+		n        Normalization
+		username string
+		expected string
+	}{
+		{0, "AdMin", "admin"},
+		{FoldCase, "A.d.m.i.n", "a.d.m.i.n"},
+		{StripSeparators, "A.d-m_i.n", "Admin"},
+		{FoldCase | Leet, "4DM1N", "admin"},
+		{FoldCase | CollapseRepeats, "AAdmmiin", "admin"},
+		{NormalizeAll, "4.d.m.1.n", "admin"},
+		{NormalizeAll, "aa.d-d_m1n", "admin"},
+		{NormalizeAll, "zoë.smith", "zoësmith"},
+	}
+	for _, v := range normalizeTestCases {
+		p := all
+		p.Normalization = v.n
+		require.Equal(t, v.expected, p.Normalize(v.username), v.username)
+	}
+
+	t.Run("Blacklist", func(t *testing.T) {
+		for _, s := range []string{"Adm1n", "a.d.m.i.n", "4dmin", "aadmiin", "0racl3"} {
+			p := all
+			p.Reserved = []string{"admin"}
+			err := NewWithPolicy(p, s).Validate()
+			var ve *ValidationError
+			require.ErrorAs(t, err, &ve, s)
+			require.Equal(t, CodeBlacklisted, ve.Code, s)
+			require.NotEmpty(t, ve.Match, s)
+			require.Equal(t, p.Normalize(s), ve.Normalized, s)
+		}
+
+		p := all
+		p.Reserved = []string{"Acme.Pay"}
+		err := NewWithPolicy(p, "acme-p4y").Validate()
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, "Acme.Pay", ve.Match)
+		require.Equal(t, "acmepay", ve.Normalized)
+
+		require.NoError(t, NewWithPolicy(all, "sarah.adams").Validate())
+		require.NoError(t, New("Adm1n.x").Validate())
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		err := New("Oracle").Validate()
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, "oracle", ve.Match)
+		require.Equal(t, "oracle", ve.Normalized)
+		require.NoError(t, New("0racle").Validate())
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		c, err := LoadConfig(strings.NewReader(
+			"blacklist:\n  builtin: true\n  normalize: [fold_case, leet]\n"), FormatYAML)
+		require.NoError(t, err)
+		require.Equal(t, FoldCase|Leet, c.Policy().Normalization)
+		require.Error(t, c.Identity("0racle").Validate())
+
+		_, err = LoadConfig(strings.NewReader(
+			"blacklist:\n  normalize: [fold_case, soundex]\n"), FormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "blacklist.normalize[1]", ce.Path)
+	})
+
+	t.Run("LeetT", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Normalization = Leet
+		require.Equal(t, "tastsb", p.Normalize("7a$+58"))
+	})
+}
+
+func TestMatcher(t *testing.T) {
+	t.Parallel()
+
+	p := DefaultPolicy()
+	p.Separators = "._"
+	p.MaxSeparators = -1
+	p.Reserved = []string{
+		"contains:admin", "prefix:acme", "suffix:bot", "glob:test*user",
+		"regex:^x{3,}", "exact:ceo",
+	}
+	m, err := p.Matcher()
+	require.NoError(t, err)
+
+	var matcherTestCases = []struct {
+		username string
+		entry    string
+	}{
+		{username: "adminsupport", entry: "contains:admin"},
+		{username: "official_admin", entry: "contains:admin"},
+		{username: "xxadminxx", entry: "contains:admin"},
+		{username: "acmepay", entry: "prefix:acme"},
+		{username: "chat.bot", entry: "suffix:bot"},
+		{username: "TestPowerUser", entry: "glob:test*user"},
+		{username: "xxxyz", entry: "regex:^x{3,}"},
+		{username: "CEO", entry: "exact:ceo"},
+		{username: "oracle", entry: "oracle"},
+		{username: "payacme"},
+		{username: "bots4u"},
+		{username: "ceos"},
+		{username: "testuser.x"},
+		{username: "xxyz"},
+		{username: "sarah.adams"},
+	}
+	for _, v := range matcherTestCases {
+		entry, ok := m.Match(v.username)
+		require.Equal(t, v.entry != "", ok, v.username)
+		require.Equal(t, v.entry, entry, v.username)
+	}
+
+	t.Run("Validate", func(t *testing.T) {
+		err := NewWithPolicy(p, "acme_support").Validate()
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, CodeBlacklisted, ve.Code)
+		require.Equal(t, "prefix:acme", ve.Match)
+
+		all := p
+		all.Normalization = NormalizeAll
+		require.ErrorAs(t, NewWithPolicy(all, "super.4dm1n").Validate(), &ve)
+		require.Equal(t, "contains:admin", ve.Match)
+		require.Equal(t, "superadmin", ve.Normalized)
+	})
+
+	t.Run("InvalidEntry", func(t *testing.T) {
+		bad := DefaultPolicy()
+		bad.Reserved = []string{"acme", "regex:adm(in"}
+		_, err := bad.Matcher()
+		require.ErrorContains(t, err, `blacklist entry "regex:adm(in"`)
+		require.ErrorContains(t, NewWithPolicy(bad, "sarah.adams").Validate(),
+			`blacklist entry "regex:adm(in"`)
+
+		_, err = LoadConfig(strings.NewReader(
+			"reserved: [acme, \"regex:adm(in\"]\n"), FormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "reserved[1]", ce.Path)
+	})
+
+	t.Run("EmptyPattern", func(t *testing.T) {
+		for _, entry := range []string{"regex:", "glob:"} {
+			require.ErrorContains(t, checkEntry(entry), "is empty", entry)
+
+			bad := DefaultPolicy()
+			bad.Reserved = []string{entry}
+			_, err := bad.Matcher()
+			require.ErrorContains(t, err, "is empty", entry)
+
+			_, err = LoadBlacklist(DefaultPolicy(), NewBlacklistSet("acme", entry))
+			require.ErrorContains(t, err, "is empty", entry)
+
+			_, err = LoadConfig(strings.NewReader(
+				fmt.Sprintf("blacklist:\n  words: [acme, %q]\n", entry)), FormatYAML)
+			var ce *ConfigError
+			require.ErrorAs(t, err, &ce, entry)
+			require.Equal(t, "blacklist.words[1]", ce.Path)
+		}
+	})
+
+	t.Run("AhoCorasick", func(t *testing.T) {
+		a := newAhoCorasick([]string{"he", "she", "his", "hers"})
+		var found []string
+		a.scan("ushers", func(k, start, end int) bool {
+			found = append(found, fmt.Sprintf("%d:%d-%d", k, start, end))
+			return true
+		})
+		require.Equal(t, []string{"1:1-4", "0:2-4", "3:2-6"}, found)
+
+		found = nil
+		a.scan("ushers", func(k, start, end int) bool {
+			found = append(found, fmt.Sprintf("%d:%d-%d", k, start, end))
+			return false
+		})
+		require.Equal(t, []string{"1:1-4"}, found)
+	})
+
+	t.Run("Modes", func(t *testing.T) {
+		require.Equal(t, "prefix", MatchPrefix.String())
+		require.Equal(t, "MatchMode(9)", MatchMode(9).String())
+
+		require.NoError(t, checkEntry("glob:adm*"))
+		require.ErrorContains(t, checkEntry("prefix:"), "is empty")
+		_, err := Policy{Reserved: []string{"prefix:"}}.Matcher()
+		require.ErrorContains(t, err, "is empty")
+	})
+}
+
+func TestBlacklistSource(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Sources", func(t *testing.T) {
+		entries, err := DefaultBlacklist().Entries()
+		require.NoError(t, err)
+		require.Contains(t, entries, "oracle")
+
+		path := filepath.Join(t.TempDir(), "reserved.txt")
+		require.NoError(t, os.WriteFile(path, []byte("# staff\nacme\n\ncontains:admin\n"), 0o600))
+		entries, err = FileBlacklist(path).Entries()
+		require.NoError(t, err)
+		require.Equal(t, []string{"acme", "contains:admin"}, entries)
+
+		_, err = FileBlacklist(filepath.Join(t.TempDir(), "missing.txt")).Entries()
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		r := ReaderBlacklist(strings.NewReader("acme\nacmepay\n"))
+		for i := 0; i < 2; i++ {
+			entries, err = r.Entries()
+			require.NoError(t, err)
+			require.Equal(t, []string{"acme", "acmepay"}, entries)
+		}
+
+		set := NewBlacklistSet("zeta", "acme").Add("beta").Remove("zeta")
+		entries, err = set.Entries()
+		require.NoError(t, err)
+		require.Equal(t, []string{"acme", "beta"}, entries)
+
+		entries, err = MergeBlacklists(set, FileBlacklist(path), NewBlacklistSet("gamma")).Entries()
+		require.NoError(t, err)
+		require.Equal(t, []string{"acme", "beta", "contains:admin", "gamma"}, entries)
+
+		errSource := errors.New("source down")
+		_, err = MergeBlacklists(set, BlacklistFunc(func() ([]string, error) {
+			return nil, errSource
+		})).Entries()
+		require.ErrorIs(t, err, errSource)
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		set := NewBlacklistSet("acmepay")
+		p := DefaultPolicy()
+		live, err := LoadBlacklist(p, MergeBlacklists(DefaultBlacklist(), set))
+		require.NoError(t, err)
+		p.Live = live
+
+		u := NewWithPolicy(p, "acmepay")
+		require.ErrorIs(t, u.Validate(), ErrBlacklisted)
+		require.ErrorIs(t, u.On("oracle").Validate(), ErrBlacklisted)
+		require.NoError(t, u.On("sarah.adams").Validate())
+
+		set.Add("sarah.adams")
+		require.NoError(t, u.Validate())
+		require.NoError(t, live.Reload())
+		require.ErrorIs(t, u.Validate(), ErrBlacklisted)
+
+		set.Add("regex:adm(in")
+		require.ErrorContains(t, live.Reload(), "adm(in")
+		require.ErrorIs(t, u.Validate(), ErrBlacklisted)
+		entry, ok := live.Match("acmepay")
+		require.True(t, ok)
+		require.Equal(t, "acmepay", entry)
+
+		_, err = LoadBlacklist(p, set)
+		require.Error(t, err)
+
+		errSource := errors.New("source down")
+		var down atomic.Bool
+		live, err = LoadBlacklist(p, BlacklistFunc(func() ([]string, error) {
+			if down.Load() {
+				return nil, errSource
+			}
+			return []string{"acmepay"}, nil
+		}))
+		require.NoError(t, err)
+		down.Store(true)
+		require.ErrorIs(t, live.Reload(), errSource)
+		_, ok = live.Match("acmepay")
+		require.True(t, ok)
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "reserved.txt")
+		require.NoError(t, os.WriteFile(path, []byte("acme\n"), 0o600))
+
+		live, err := LoadBlacklist(DefaultPolicy(), MergeBlacklists(FileBlacklist(path)))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- live.Watch(ctx, 5*time.Millisecond, nil) }()
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						live.Match("acme")
+					}
+				}
+			}()
+		}
+
+		_, ok := live.Match("moree.hq")
+		require.False(t, ok)
+		require.NoError(t, os.WriteFile(path, []byte("acme\nmoree.hq\n"), 0o600))
+		require.Eventually(t, func() bool {
+			_, ok := live.Match("moree.hq")
+			return ok
+		}, 5*time.Second, 5*time.Millisecond)
+
+		close(stop)
+		wg.Wait()
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+
+		set, err := LoadBlacklist(DefaultPolicy(), NewBlacklistSet("acme"))
+		require.NoError(t, err)
+		require.Error(t, set.Watch(context.Background(), time.Second, nil))
+	})
+
+	t.Run("WatchRetry", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "reserved.txt")
+		require.NoError(t, os.WriteFile(path, []byte("acme\n"), 0o600))
+		live, err := LoadBlacklist(DefaultPolicy(), FileBlacklist(path))
+		require.NoError(t, err)
+
+		// A failed reload is retried until the file is fixed.
+		require.NoError(t, os.WriteFile(path, []byte("acme\nregex:(\n"), 0o600))
+		var failures atomic.Int32
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go live.Watch(ctx, 5*time.Millisecond, func(error) { failures.Add(1) })
+		require.Eventually(t, func() bool { return failures.Load() >= 2 },
+			5*time.Second, 5*time.Millisecond)
+
+		require.NoError(t, os.WriteFile(path, []byte("acme\nmoree.hq\n"), 0o600))
+		require.Eventually(t, func() bool {
+			_, ok := live.Match("moree.hq")
+			return ok
+		}, 5*time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestAllowlist(t *testing.T) {
+	t.Parallel()
+
+	allow, err := NewAllowlist(
+		AllowEntry{Pattern: "billing", Rules: []string{RuleIntegrity}},
+		AllowEntry{Pattern: "glob:op?", Rules: []string{RuleRange}},
+		AllowEntry{Pattern: "glob:bill*", Rules: []string{RuleIntegrity}},
+		AllowEntry{Pattern: "legacy.root"},
+	)
+	require.NoError(t, err)
+
+	var allowTestCases = []struct {
+		username string
+		rule     string
+		pattern  string
+	}{
+		{username: "Billing", rule: RuleIntegrity, pattern: "billing"},
+		{username: "ops", rule: RuleRange, pattern: "glob:op?"},
+		{username: "legacy.root", rule: RuleFormat, pattern: "legacy.root"},
+		{username: "legacy.root", rule: "", pattern: "legacy.root"},
+		{username: "billing", rule: RuleRange},
+		{username: "billing", rule: ""},
+		{username: "opsx", rule: RuleRange},
+	}
+	for _, v := range allowTestCases {
+		pattern, ok := allow.Allows(v.username, v.rule)
+		require.Equal(t, v.pattern != "", ok, "%s %s", v.username, v.rule)
+		require.Equal(t, v.pattern, pattern)
+	}
+
+	t.Run("Validate", func(t *testing.T) {
+		u := New("billing").WithAllowlist(allow)
+		require.NoError(t, u.Validate())
+		require.NoError(t, u.ValidateAll())
+
+		res := u.ValidateResult(context.Background())
+		require.True(t, res.Valid())
+		require.Len(t, res.Bypassed, 1)
+		require.Equal(t, RuleIntegrity, res.Bypassed[0].Rule)
+		require.Equal(t, "billing", res.Bypassed[0].Pattern)
+		require.ErrorIs(t, res.Bypassed[0].Err, ErrBlacklisted)
+
+		res = u.On("ops").ValidateResult(context.Background())
+		require.True(t, res.Valid())
+		require.Equal(t, RuleRange, res.Bypassed[0].Rule)
+
+		// The entry only covers the rule it names.
+		res = u.On("bill.in.g").ValidateResult(context.Background())
+		require.False(t, res.Valid())
+		require.ErrorIs(t, res.Err, ErrTooManySeps)
+		require.Empty(t, res.Bypassed)
+
+		res = New("sarah.adams").ValidateResult(context.Background())
+		require.True(t, res.Valid())
+		require.Nil(t, res.Bypassed)
+
+		require.ErrorIs(t, New("billing").Validate(), ErrBlacklisted)
+	})
+
+	t.Run("Unscoped", func(t *testing.T) {
+		u := New("legacy.root").WithAllowlist(allow).
+			AddValidator(func(string) (bool, error) { return false, nil })
+		res := u.ValidateResult(context.Background())
+		require.True(t, res.Valid())
+		require.Len(t, res.Bypassed, 1)
+		require.Equal(t, "", res.Bypassed[0].Rule)
+		require.ErrorIs(t, res.Bypassed[0].Err, ErrInvalid)
+
+		errStore := errors.New("store down")
+		u.AddValidatorCtx(func(context.Context, string) error { return errStore })
+		require.ErrorIs(t, u.ValidateContext(context.Background()), errStore)
+
+		// Failures that are not a *ValidationError are never allowed,
+		// from either kind of validator.
+		errTaken := errors.New("this username is unavailable")
+		u = New("legacy.root").WithAllowlist(allow).
+			AddValidator(func(string) (bool, error) { return false, errTaken })
+		require.ErrorIs(t, u.Validate(), errTaken)
+		res = u.ValidateResult(context.Background())
+		require.ErrorIs(t, res.Err, errTaken)
+		require.Empty(t, res.Bypassed)
+	})
+
+	t.Run("Suggestions", func(t *testing.T) {
+		support, err := NewAllowlist(AllowEntry{Pattern: "support"})
+		require.NoError(t, err)
+		u := New("suport").WithAllowlist(support).WithSuggestor(
+			func(string) string { return "support" },
+			func(s string) string { return s + "1" },
+		)
+		require.NoError(t, u.On("support").Validate())
+
+		u.On("suport")
+		require.Equal(t, []string{"suport1"}, u.Suggest(5))
+		suggestions, err := u.SuggestAvailable(context.Background(), 1, NewMemoryChecker())
+		require.NoError(t, err)
+		require.Equal(t, []string{"suport1"}, suggestions)
+	})
+
+	t.Run("Engine", func(t *testing.T) {
+		e := New().WithAllowlist(allow).Engine()
+		require.NoError(t, e.Validate(context.Background(), "billing"))
+		res := e.ValidateResult(context.Background(), "ops")
+		require.True(t, res.Valid())
+		require.Len(t, res.Bypassed, 1)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := NewAllowlist(AllowEntry{Pattern: "regex:("})
+		require.Error(t, err)
+
+		_, err = LoadConfig(strings.NewReader(
+			"allowlist:\n  - pattern: support\n  - pattern: \"regex:(\"\n"), FormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "allowlist[1].pattern", ce.Path)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		c, err := LoadConfig(strings.NewReader(
+			"allowlist:\n  - pattern: billing\n    rules: [integrity]\n"), FormatYAML)
+		require.NoError(t, err)
+		require.NoError(t, c.Identity("billing").Validate())
+		require.ErrorIs(t, c.Identity("oracle").Validate(), ErrBlacklisted)
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var none *Allowlist
+		_, ok := none.Allows("billing", "")
+		require.False(t, ok)
+
+		res := New("moree").AddValidator(func(string) (bool, error) { return false, nil }).
+			ValidateResult(context.Background())
+		require.ErrorIs(t, res.Err, ErrInvalid)
+	})
+}
+
+func TestProfanity(t *testing.T) {
+	t.Parallel()
+
+	p := DefaultPolicy()
+	p.Profanity = &Profanity{}
+	validate := p.profanityValidator()
+
+	var profanityTestCases = []struct {
+		username string
+		match    string
+		severity Severity
+	}{
+		{username: "xx.sh1t.xx", match: "shit", severity: SeverityStrong},
+		{username: "Bullsh1t", match: "bullshit", severity: SeverityStrong},
+		{username: "sh1t99", match: "shit", severity: SeverityStrong},
+		{username: "x.sh1thead", match: "shithead", severity: SeverityStrong},
+		{username: "the.dick", match: "dick", severity: SeverityStrong},
+		{username: "a.s.s", match: "ass", severity: SeverityMild},
+		{username: "F.U.C.K", match: "fuck", severity: SeveritySevere},
+		{username: "crapshoot", match: "crap", severity: SeverityMild},
+		{username: "shitfuck", match: "fuck", severity: SeveritySevere},
+		{username: "pendejo99", match: "pendejo", severity: SeverityStrong},
+		{username: "Scheiße", match: "scheiße", severity: SeverityMild},
+		{username: "cockpitcock", match: "cock", severity: SeverityStrong},
+		{username: "ass", match: "ass", severity: SeverityMild},
+		{username: "scunthorpe"},
+		{username: "Scunthorpe.United"},
+		{username: "cocktail_bar"},
+		{username: "therapist"},
+		{username: "computer"},
+		{username: "classic"},
+		{username: "assassin"},
+		{username: "title"},
+		{username: "sarah.adams"},
+		{username: "yamashita"},
+		{username: "kinoshita"},
+		{username: "larsen"},
+		{username: "marseille"},
+		{username: "enviado"},
+		{username: "amputate"},
+		{username: "dickerson"},
+		{username: "ashkenazi"},
+		{username: "peniston"},
+		{username: "adamnelson"},
+		{username: "maricarmen"},
+		{username: "porras"},
+	}
+	for _, v := range profanityTestCases {
+		ok, err := validate(v.username)
+		if v.match == "" {
+			require.True(t, ok, v.username)
+			require.NoError(t, err, v.username)
+			continue
+		}
+		require.False(t, ok, v.username)
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve, v.username)
+		require.Equal(t, RuleProfanity, ve.Rule)
+		require.Equal(t, CodeProfane, ve.Code)
+		require.Equal(t, v.match, ve.Match, v.username)
+		require.Equal(t, v.severity, ve.Severity, v.username)
+		require.ErrorIs(t, err, ErrProfane)
+		require.NotContains(t, err.Error(), v.match)
+	}
+
+	t.Run("Severity", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Profanity = &Profanity{MinSeverity: SeverityStrong}
+		validate := p.profanityValidator()
+		ok, _ := validate("crapshoot")
+		require.True(t, ok)
+		ok, _ = validate("bitchy")
+		require.False(t, ok)
+
+		require.Equal(t, "severe", SeveritySevere.String())
+		require.Equal(t, "Severity(9)", Severity(9).String())
+		s, ok := parseSeverity("Strong")
+		require.True(t, ok)
+		require.Equal(t, SeverityStrong, s)
+		_, ok = parseSeverity("")
+		require.False(t, ok)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Profanity = &Profanity{
+			Languages:  []string{"en"},
+			Terms:      []ProfanityTerm{{Term: "frak", Severity: SeverityMild}},
+			Exceptions: []string{"shitzu"},
+		}
+		validate := p.profanityValidator()
+		ok, _ := validate("frakker")
+		require.False(t, ok)
+		ok, _ = validate("pendejo99")
+		require.True(t, ok)
+		ok, _ = validate("my.shitzu")
+		require.True(t, ok)
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		u := NewWithPolicy(p, "sh1t.lord")
+		require.ErrorIs(t, u.Validate(), ErrProfane)
+		require.NoError(t, u.On("cocktail").Validate())
+		require.NoError(t, u.On("yamashita").Validate())
+		require.NoError(t, New("sh1t.lord").Validate())
+
+		// The example of the README.
+		p := DefaultPolicy()
+		p.Profanity = &Profanity{MinSeverity: SeverityStrong, Languages: []string{"en", "es"}}
+		require.ErrorIs(t, NewWithPolicy(p, "the.sh1t").Validate(), ErrProfane)
+		for _, s := range NewWithPolicy(p, "gamer").Suggest(50) {
+			ok, _ := validate(s)
+			require.True(t, ok, s)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		doc := "profanity:\n  enabled: true\n  min_severity: strong\n  words: [frak]\n"
+		c, err := LoadConfig(strings.NewReader(doc), FormatYAML)
+		require.NoError(t, err)
+		u := c.Identity("frakker")
+		require.ErrorIs(t, u.Validate(), ErrProfane)
+		require.ErrorIs(t, u.On("bitchy").Validate(), ErrProfane)
+		require.NoError(t, u.On("crapshoot").Validate())
+
+		_, err = LoadConfig(strings.NewReader(
+			"profanity:\n  min_severity: rude\n"), FormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "profanity.min_severity", ce.Path)
+
+		_, err = LoadConfig(strings.NewReader(
+			"profanity:\n  languages: [en, xx]\n"), FormatYAML)
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "profanity.languages[1]", ce.Path)
+	})
+}
+
+func TestCategories(t *testing.T) {
+	t.Parallel()
+
+	for category, words := range blacklistCategories {
+		require.Contains(t, Categories(), category)
+		for _, w := range words {
+			require.Equal(t, category, builtinCategory[w], w)
+			_, found := slices.BinarySearch(blacklist, w)
+			require.True(t, found, w)
+		}
+	}
+	require.Equal(t, []string{"facebook", "github"}, CategoryWords(CategoryBrands)[3:5])
+	require.Contains(t, CategoryWords(CategoryCommon), "welcome")
+	require.Nil(t, CategoryWords("unknown"))
+
+	var categoryTestCases = []struct {
+		username string
+		category string
+	}{
+		{username: "Admin", category: CategorySystem},
+		{username: "checkout", category: CategoryRoutes},
+		{username: "github", category: CategoryBrands},
+		{username: "ctxtestuser", category: CategoryTest},
+		{username: "welcome", category: CategoryCommon},
+	}
+	for _, v := range categoryTestCases {
+		var ve *ValidationError
+		require.ErrorAs(t, New(v.username).Validate(), &ve)
+		require.Equal(t, v.category, ve.Category, v.username)
+	}
+
+	t.Run("Policy", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Reserved = []string{"acmepay"}
+		p.Categories = map[string][]string{
+			"product":  {"acmecloud", "prefix:acmedrive"},
+			"features": {"insights"},
+		}
+		p.DisabledCategories = []string{CategoryRoutes, "features"}
+		u := NewWithPolicy(p, "checkout")
+		require.NoError(t, u.Validate())
+		require.NoError(t, u.On("insights").Validate())
+
+		var ve *ValidationError
+		require.ErrorAs(t, u.On("acmedrive.eu").Validate(), &ve)
+		require.Equal(t, "product", ve.Category)
+		require.Equal(t, "prefix:acmedrive", ve.Match)
+		require.ErrorAs(t, u.On("acmepay").Validate(), &ve)
+		require.Equal(t, "", ve.Category)
+		require.ErrorAs(t, u.On("hostmaster").Validate(), &ve)
+		require.Equal(t, CategorySystem, ve.Category)
+
+		m, err := p.Matcher()
+		require.NoError(t, err)
+		entry, category, ok := m.MatchCategory("AcmeCloud")
+		require.True(t, ok)
+		require.Equal(t, "acmecloud", entry)
+		require.Equal(t, "product", category)
+
+		// A custom Blacklist has no built-in categories.
+		p.Blacklist = []string{"checkout"}
+		require.ErrorAs(t, NewWithPolicy(p, "checkout").Validate(), &ve)
+		require.Equal(t, "", ve.Category)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		doc := "categories:\n  product: [acmecloud]\nblacklist:\n  disabled_categories: [brands, product]\n"
+		c, err := LoadConfig(strings.NewReader(doc), FormatYAML)
+		require.NoError(t, err)
+		u := c.Identity("github")
+		require.NoError(t, u.Validate())
+		require.NoError(t, u.On("acmecloud").Validate())
+		require.ErrorIs(t, u.On("admin").Validate(), ErrBlacklisted)
+
+		// Custom words keep the categories of the built-in list.
+		doc = "blacklist:\n  words: [staff]\n  disabled_categories: [routes]\n"
+		c, err = LoadConfig(strings.NewReader(doc), FormatYAML)
+		require.NoError(t, err)
+		u = c.Identity("login")
+		require.NoError(t, u.Validate())
+		require.ErrorIs(t, u.On("staff").Validate(), ErrBlacklisted)
+		var ve *ValidationError
+		require.ErrorAs(t, u.On("admin").Validate(), &ve)
+		require.Equal(t, CategorySystem, ve.Category)
+
+		_, err = LoadConfig(strings.NewReader(
+			"blacklist:\n  disabled_categories: [routes, nope]\n"), FormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "blacklist.disabled_categories[1]", ce.Path)
+
+		_, err = LoadConfig(strings.NewReader(
+			"categories:\n  product: [acmecloud, \"regex:(\"]\n"), FormatYAML)
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "categories.product[1]", ce.Path)
+		require.Equal(t, 2, ce.Line)
+	})
+
+	t.Run("Live", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.DisabledCategories = []string{CategoryRoutes}
+		live, err := LoadBlacklist(p, MergeBlacklists(DefaultBlacklist(), NewBlacklistSet("acme")))
+		require.NoError(t, err)
+		p.Live = live
+
+		u := NewWithPolicy(p, "login")
+		require.NoError(t, u.Validate())
+		var ve *ValidationError
+		require.ErrorAs(t, u.On("admin").Validate(), &ve)
+		require.Equal(t, CategorySystem, ve.Category)
+		require.ErrorAs(t, u.On("acme").Validate(), &ve)
+		require.Equal(t, "", ve.Category)
+
+		require.NoError(t, live.Reload())
+		require.NoError(t, u.On("checkout").Validate())
+	})
+}
+
+func TestRoutes(t *testing.T) {
+	t.Parallel()
+
+	var routeSegmentTestCases = []struct {
+		pattern string
+		segment string
+		err     bool
+	}{
+		{pattern: "/api/", segment: "api"},
+		{pattern: "GET /Checkout/{id}", segment: "checkout"},
+		{pattern: "example.com/static/{path...}", segment: "static"},
+		{pattern: "POST example.com/billing", segment: "billing"},
+		{pattern: "docs", segment: "docs"},
+		{pattern: "/caf%C3%A9", segment: "café"},
+		{pattern: "/{user}"},
+		{pattern: "/{user}/settings"},
+		{pattern: "/{$}"},
+		{pattern: "/"},
+		{pattern: "", err: true},
+		{pattern: "/user{id}", err: true},
+		{pattern: "/%zz", err: true},
+	}
+	for _, v := range routeSegmentTestCases {
+		segment, err := routeSegment(v.pattern)
+		require.Equal(t, v.err, err != nil, v.pattern)
+		require.Equal(t, v.segment, segment, v.pattern)
+	}
+
+	p := DefaultPolicy()
+	p.Routes = &Routes{Patterns: []string{"GET /pricing/{plan}", "/{user}", "/teams/"}}
+	validate := p.routeValidator()
+
+	var routeTestCases = []struct {
+		username string
+		match    string
+	}{
+		{username: "pricing", match: "GET /pricing/{plan}"},
+		{username: "Teams", match: "/teams/"},
+		{username: "favicon.ico", match: "favicon.ico"},
+		{username: "well-known", match: "well-known"},
+		{username: "CON", match: "con"},
+		{username: "nul.txt", match: "nul"},
+		{username: "lpt1.tar.gz", match: "lpt1"},
+		{username: "console"},
+		{username: "apiary"},
+		{username: "user"},
+		{username: "sarah.adams"},
+	}
+	for _, v := range routeTestCases {
+		ok, err := validate(v.username)
+		if v.match == "" {
+			require.True(t, ok, v.username)
+			require.NoError(t, err, v.username)
+			continue
+		}
+		require.False(t, ok, v.username)
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve, v.username)
+		require.Equal(t, RuleRoute, ve.Rule)
+		require.Equal(t, v.match, ve.Match, v.username)
+		require.ErrorIs(t, err, ErrRouteCollision)
+	}
+
+	t.Run("Allow", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Routes = &Routes{AllowWellKnown: true, AllowReservedFilenames: true}
+		validate := p.routeValidator()
+		for _, name := range []string{"static", "con.txt", "favicon.ico"} {
+			ok, err := validate(name)
+			require.True(t, ok, name)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		u := NewWithPolicy(p, "pricing")
+		err := u.Validate()
+		require.ErrorIs(t, err, ErrRouteCollision)
+		require.EqualError(t, err, `username "pricing" is reserved by the site, please choose a different one`)
+		require.NoError(t, u.On("sarah.adams").Validate())
+
+		bad := DefaultPolicy()
+		bad.Routes = &Routes{Patterns: []string{"/user{id}"}}
+		require.ErrorContains(t, NewWithPolicy(bad, "sarah").Validate(), "whole segment")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		doc := `{"routes": {"enabled": true, "patterns": ["/pricing/", "GET /{user}"]}}`
+		c, err := LoadConfig(strings.NewReader(doc), FormatJSON)
+		require.NoError(t, err)
+		require.ErrorIs(t, c.Identity("pricing").Validate(), ErrRouteCollision)
+		require.ErrorIs(t, c.Identity("robots.txt").Validate(), ErrRouteCollision)
+
+		_, err = LoadConfig(strings.NewReader(
+			`{"routes": {"patterns": ["/ok", "/{bad"]}}`), FormatJSON)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "routes.patterns[1]", ce.Path)
+	})
+
+	t.Run("DotSegments", func(t *testing.T) {
+		for _, name := range []string{".", ".."} {
+			match, ok := reservedFilename(name)
+			require.True(t, ok, name)
+			require.Equal(t, name, match)
+		}
+	})
+}
+
+type similarFunc func(ctx context.Context, name string, maxDistance int) ([]SimilarName, error)
+
+func (f similarFunc) FindSimilar(ctx context.Context, name string, maxDistance int) ([]SimilarName, error) {
+	return f(ctx, name, maxDistance)
+}
+
+func TestSimilarity(t *testing.T) {
+	t.Parallel()
+
+	var editDistanceTestCases = []struct {
+		a, b     string
+		distance int
+	}{
+		{a: "", b: "", distance: 0},
+		{a: "", b: "abc", distance: 3},
+		{a: "john.smith", b: "john.smith", distance: 0},
+		{a: "john.smith", b: "jonh.smith", distance: 1},
+		{a: "john.smith", b: "john.smyth", distance: 1},
+		{a: "john.smith", b: "johnsmith", distance: 1},
+		{a: "ca", b: "abc", distance: 2},
+		{a: "kitten", b: "sitting", distance: 3},
+		{a: "émile", b: "méile", distance: 1},
+		{a: "émile", b: "emile", distance: 1},
+	}
+	for _, v := range editDistanceTestCases {
+		require.Equal(t, v.distance, EditDistance(v.a, v.b), "%s %s", v.a, v.b)
+		require.Equal(t, v.distance, EditDistance(v.b, v.a), "%s %s", v.b, v.a)
+	}
+
+	var buf editBuffer
+	require.Equal(t, 2, buf.distance("kitten", "sitting", 1))
+	require.Equal(t, 2, buf.distance("abc", "abcdefgh", 1))
+	require.Equal(t, 3, buf.distance("kitten", "sitting", 3))
+
+	index := NewSimilarIndex(DefaultPolicy(), "john.smith", "John.Smith", "moree", "sarah.adams")
+	require.Equal(t, 4, index.Len())
+	index.Add("moree")
+	require.Equal(t, 4, index.Len())
+
+	found, err := index.FindSimilar(context.Background(), "jonh.smith", 1)
+	require.NoError(t, err)
+	require.Equal(t, []SimilarName{{Name: "John.Smith", Distance: 1}, {Name: "john.smith", Distance: 1}}, found)
+
+	found, err = index.FindSimilar(context.Background(), "john.srnith", 0)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+
+	found, err = index.FindSimilar(context.Background(), "sarah.adamz", 2)
+	require.NoError(t, err)
+	require.Equal(t, []SimilarName{{Name: "sarah.adams", Distance: 1}}, found)
+
+	found, err = NewSimilarIndex(DefaultPolicy()).FindSimilar(context.Background(), "moree", 1)
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	t.Run("Validator", func(t *testing.T) {
+		u := New("jonh.smith").WithSimilarity(index, 1)
+		err := u.ValidateContext(context.Background())
+		require.ErrorIs(t, err, ErrTooSimilar)
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, RuleSimilarity, ve.Rule)
+		require.Equal(t, 1, ve.Distance)
+		require.EqualError(t, err, `username is too similar to "John.Smith", please choose a different one`)
+
+		require.NoError(t, u.On("moree").ValidateContext(context.Background()))
+		require.NoError(t, u.On("jane.doe").ValidateContext(context.Background()))
+		require.ErrorIs(t, u.On("moree2").ValidateContext(context.Background()), ErrTooSimilar)
+
+		// Suggestions never return the protected name itself.
+		suggestions, err := New("moree1").WithSimilarity(index, 0).
+			AddSuggestor(func(s string) string { return strings.TrimSuffix(s, "1") }).
+			SuggestContext(context.Background(), 17)
+		require.NoError(t, err)
+		require.NotContains(t, suggestions, "moree")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, u.On("jane.doe").ValidateContext(ctx), context.Canceled)
+	})
+
+	t.Run("Normalization", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.Separators = "._"
+		p.Normalization = FoldCase | StripSeparators
+		index := NewSimilarIndex(p, "john.smith")
+		found, err := index.FindSimilar(context.Background(), "john_smith", 0)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+	})
+
+	t.Run("BKTree", func(t *testing.T) {
+		r := rand.New(rand.NewPCG(3, 4))
+		var names []string
+		for i := 0; i < 2000; i++ {
+			n := make([]byte, 4+r.IntN(5))
+			for j := range n {
+				n[j] = "abcde"[r.IntN(5)]
+			}
+			names = append(names, string(n))
+		}
+		index := NewSimilarIndex(DefaultPolicy(), names...)
+		for _, q := range []string{"abcde", "aaaa", "edcbae", "bbbbbbbb"} {
+			found, err := index.FindSimilar(context.Background(), q, 2)
+			require.NoError(t, err)
+			var want []string
+			for _, n := range names {
+				if EditDistance(q, n) <= 2 && !slices.Contains(want, n) {
+					want = append(want, n)
+				}
+			}
+			got := make([]string, len(found))
+			for i, f := range found {
+				got[i] = f.Name
+				require.Equal(t, EditDistance(q, f.Name), f.Distance)
+			}
+			require.ElementsMatch(t, want, got, q)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		index := NewSimilarIndex(DefaultPolicy(), "john.smith")
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				index.Add(fmt.Sprintf("user%d", i))
+				_, err := index.FindSimilar(context.Background(), "jonh.smith", 1)
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+		require.Equal(t, 5, index.Len())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := index.FindSimilar(ctx, "moree", 1)
+		require.ErrorIs(t, err, context.Canceled)
+
+		errStore := errors.New("store down")
+		v := SimilarityValidator(similarFunc(func(context.Context, string, int) ([]SimilarName, error) {
+			return nil, errStore
+		}), 1)
+		require.ErrorIs(t, v(context.Background(), "moree"), errStore)
+	})
+}
+
+func TestCanonicalize(t *testing.T) {
+	t.Parallel()
+
+	var canonicalTestCases = []struct {
+		canonical Canonicalization
+		name      string
+		key       string
+	}{
+		{name: "John.Smith", key: "john.smith"},
+		{name: "\uff2a\uff4f\uff48\uff4e", key: "john"},
+		{name: "Straße", key: "strasse"},
+		{name: "émile", key: "émile"},
+		{name: "Émile", key: "émile"},
+		{canonical: IgnoreSeparators, name: "John.Smith", key: "johnsmith"},
+		{canonical: IgnoreSeparators, name: "Jöhn.Smith", key: "jöhnsmith"},
+		{canonical: IgnoreConfusables, name: "PayPa1", key: "paypal"},
+		{canonical: IgnoreConfusables, name: "p\u0430ypal", key: "paypal"},
+		{canonical: IgnoreSeparators | IgnoreConfusables, name: "Pay.Pa1", key: "paypal"},
+	}
+	for _, v := range canonicalTestCases {
+		p := DefaultPolicy()
+		p.Canonicalization = v.canonical
+		key := p.Canonicalize(v.name)
+		require.Equal(t, v.key, key, v.name)
+		require.Equal(t, key, p.Canonicalize(key), v.name)
+	}
+
+	p := DefaultPolicy()
+	require.True(t, p.Equivalent("John.Smith", "john.smith"))
+	require.False(t, p.Equivalent("john.smith", "johnsmith"))
+	p.Canonicalization = IgnoreSeparators
+	require.True(t, p.Equivalent("john.smith", "johnsmith"))
+	require.False(t, p.Equivalent("paypal", "paypa1"))
+
+	t.Run("Config", func(t *testing.T) {
+		c, err := LoadConfig(strings.NewReader(
+			`{"canonical": {"ignore_separators": true, "ignore_confusables": true}}`), FormatJSON)
+		require.NoError(t, err)
+		require.True(t, c.Policy().Equivalent("Pay.Pa1", "paypal"))
+	})
+}
+
+func TestScore(t *testing.T) {
+	t.Parallel()
+
+	p := DefaultPolicy()
+	for _, name := range []string{"aaaaa1", "user12345", "admin", "zzzzzzzz", "qwerty123"} {
+		require.Less(t, p.Score(name).Total, 40, name)
+	}
+	for _, name := range []string{"sarah.adams", "gamer_tag", "thunderbolt", "johnsmith"} {
+		require.Greater(t, p.Score(name).Total, 75, name)
+	}
+	require.Greater(t, p.Score("johnsmith").Total, p.Score("jsmith").Total)
+
+	var scoreFactorTestCases = []struct {
+		name   string
+		factor string
+		value  float64
+	}{
+		{name: "abc", factor: "length", value: 0},
+		{name: "sarah.adams", factor: "length", value: 1},
+		{name: "zzzzzzzz", factor: "entropy", value: 0},
+		{name: "abcdefgh", factor: "entropy", value: 1},
+		{name: "12345678", factor: "digits", value: 0},
+		{name: "gamer1", factor: "digits", value: 1},
+		{name: "aaaaa1", factor: "repeats", value: 0},
+		{name: "sarah12", factor: "repeats", value: 2.0 / 3},
+		{name: "testuser", factor: "words", value: 0},
+		{name: "thunderbolt", factor: "words", value: 1},
+		{name: "xkcdqwrt", factor: "pronounceability", value: 0},
+		{name: "strengths", factor: "pronounceability", value: 1 - 4.0/9},
+		{name: "admin", factor: "blacklist", value: 0},
+		{name: "adm1n", factor: "blacklist", value: 1.0 / 3},
+		{name: "thunderbolt", factor: "blacklist", value: 1},
+	}
+	for _, v := range scoreFactorTestCases {
+		f := p.Score(v.name).Factor(v.factor)
+		require.Equal(t, v.factor, f.Name)
+		require.InDelta(t, v.value, f.Value, 1e-9, "%s %s", v.name, v.factor)
+	}
+
+	s := p.Score("sarah.adams")
+	var weights int
+	for _, f := range s.Factors {
+		weights += f.Weight
+		require.GreaterOrEqual(t, f.Value, 0.0)
+		require.LessOrEqual(t, f.Value, 1.0)
+	}
+	require.Equal(t, 100, weights)
+	require.Equal(t, ScoreFactor{}, s.Factor("unknown"))
+	require.Equal(t, s, New("sarah.adams").Score())
+	require.Equal(t, 0, p.Score("").Total)
+
+	t.Run("Validator", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.MinScore = 50
+		u := NewWithPolicy(p, "user12345")
+		err := u.Validate()
+		require.ErrorIs(t, err, ErrLowScore)
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, RuleQuality, ve.Rule)
+		require.Equal(t, 50, ve.Min)
+		require.Equal(t, p.Score("user12345").Total, ve.Score)
+		require.EqualError(t, err, fmt.Sprintf(
+			"username scores %d out of 100, at least 50 is required", ve.Score))
+		require.NoError(t, u.On("sarah.adams").Validate())
+
+		ok, err := p.ScoreValidator(90)("sarah.adams")
+		require.False(t, ok)
+		require.ErrorIs(t, err, ErrLowScore)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		c, err := LoadConfig(strings.NewReader(`{"min_score": 50}`), FormatJSON)
+		require.NoError(t, err)
+		require.ErrorIs(t, c.Identity("user12345").Validate(), ErrLowScore)
+
+		_, err = LoadConfig(strings.NewReader(`{"min_score": 101}`), FormatJSON)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "min_score", ce.Path)
+	})
+
+	t.Run("Edges", func(t *testing.T) {
+		long := strings.Repeat("thunderbolt", 3)
+		require.Less(t, p.Score(long).Factor("length").Value, 1.0)
+		require.Equal(t, 1.0, p.Score("zoé.adams").Factor("pronounceability").Value)
+
+		p := DefaultPolicy()
+		p.Reserved = []string{"thunderbolt"}
+		require.Equal(t, 0.0, p.Score("thunderbolt").Factor("blacklist").Value)
+	})
+}
+
+func TestSuggestDetailed(t *testing.T) {
+	t.Parallel()
+
+	names := make(map[string]bool)
+	for _, s := range suggestorsFor('.') {
+		names[s.name] = true
+	}
+
+	u := New("moree").WithSeed(3)
+	suggestions := u.SuggestDetailed(16)
+	require.NotEmpty(t, suggestions)
+	p := DefaultPolicy()
+	for i, s := range suggestions {
+		require.True(t, names[s.Suggestor], s.Suggestor)
+		require.Equal(t, p.Score(s.Candidate).Total, s.Score, s.Candidate)
+		require.Equal(t, EditDistance("moree", s.Candidate), s.Distance, s.Candidate)
+		if i > 0 {
+			require.LessOrEqual(t, RankByScore(suggestions[i-1], s), 0)
+		}
+	}
+
+	// The same seed yields the same candidates as Suggest.
+	var candidates []string
+	for _, s := range suggestions {
+		candidates = append(candidates, s.Candidate)
+	}
+	require.ElementsMatch(t, New("moree").WithSeed(3).Suggest(16), candidates)
+
+	t.Run("Ranking", func(t *testing.T) {
+		suggestions := New("moree").WithSeed(3).WithRanking(RankByDistance).SuggestDetailed(16)
+		require.NotEmpty(t, suggestions)
+		for i := 1; i < len(suggestions); i++ {
+			require.LessOrEqual(t, suggestions[i-1].Distance, suggestions[i].Distance)
+		}
+
+		byLength := func(a, b Suggestion) int { return len(a.Candidate) - len(b.Candidate) }
+		suggestions = New("moree").WithSeed(3).WithRanking(byLength).SuggestDetailed(16)
+		for i := 1; i < len(suggestions); i++ {
+			require.LessOrEqual(t, len(suggestions[i-1].Candidate), len(suggestions[i].Candidate))
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		u := New("moree").WithSuggestor(func(s string) string { return s + "hq" })
+		require.Equal(t, []Suggestion{{
+			Candidate: "moreehq", Suggestor: "custom",
+			Score: DefaultPolicy().Score("moreehq").Total, Distance: 2,
+		}}, u.SuggestDetailed(1))
+	})
+
+	t.Run("Engine", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		suggestions, err := New().Engine().SuggestDetailed(ctx, "moree", 5)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Empty(t, suggestions)
+	})
+}
+
+func TestSuggestorRegistry(t *testing.T) {
+	t.Parallel()
+
+	names := SuggestorNames()
+	require.Len(t, names, len(defaultSuggestors()))
+	require.Contains(t, names, "VanishVowel")
+	require.Contains(t, names, "SetSuffixRandomDigit")
+
+	t.Run("Disable", func(t *testing.T) {
+		u := New("moree").DisableSuggestors("VanishVowel", "nope")
+		require.Len(t, u.Engine().suggestor, len(names)-1)
+		for seed := uint64(0); seed < 20; seed++ {
+			for _, s := range u.WithSeed(seed).SuggestDetailed(100) {
+				require.NotEqual(t, "VanishVowel", s.Suggestor)
+			}
+		}
+
+		u.EnableSuggestors("VanishVowel")
+		require.Len(t, u.Engine().suggestor, len(names))
+		require.Len(t, u.WithSuggestorWeight("VanishVowel", 0).Engine().suggestor, len(names)-1)
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		hq := func(s string) string { return s + "hq" }
+		u := New("moree").DisableSuggestors("VanishVowel").RegisterSuggestor("VanishVowel", 1, hq)
+		require.Len(t, u.suggestor, len(names))
+		require.Equal(t, "VanishVowel", u.suggestor[len(names)-1].name)
+		require.Contains(t, u.SuggestDetailed(100), Suggestion{
+			Candidate: "moreehq", Suggestor: "VanishVowel",
+			Score: DefaultPolicy().Score("moreehq").Total, Distance: 2,
+		})
+
+		u.RegisterSuggestor("HQ", 2, hq)
+		require.Len(t, u.suggestor, len(names)+1)
+		require.Equal(t, 2, u.suggestor[len(names)].weight)
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		u := New("moree").WithSuggestor().
+			RegisterSuggestor("a", 3, func(s string) string { return s + "a" }).
+			RegisterSuggestor("b", 1, func(s string) string { return s + "b" })
+
+		first := 0
+		for seed := uint64(0); seed < 1000; seed++ {
+			if u.WithSeed(seed).Suggest(1)[0] == "moreea" {
+				first++
+			}
+		}
+		require.InDelta(t, 750, first, 100)
+
+		require.Equal(t, []string{"moreeb"}, u.WithSuggestorWeight("a", 0).Suggest(2))
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		doc := "suggestions:\n  disabled: [VanishVowel]\n  weights:\n    SetSuffixRandomDigit: 2\n"
+		c, err := LoadConfig(strings.NewReader(doc), FormatYAML)
+		require.NoError(t, err)
+		for _, s := range c.Identity("moree").suggestor {
+			require.Equal(t, s.name == "VanishVowel", s.off, s.name)
+			if s.name == "SetSuffixRandomDigit" {
+				require.Equal(t, 2, s.weight)
+			}
+		}
+
+		var ce *ConfigError
+		_, err = LoadConfig(strings.NewReader(
+			"suggestions:\n  disabled: [SwapTwoChars, nope]\n"), FormatYAML)
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "suggestions.disabled[1]", ce.Path)
+
+		_, err = LoadConfig(strings.NewReader(
+			"suggestions:\n  weights:\n    VanishVowel: -1\n"), FormatYAML)
+		require.ErrorAs(t, err, &ce)
+		require.Equal(t, "suggestions.weights.VanishVowel", ce.Path)
+	})
+}
@@ -1,9 +1,11 @@
 package unamex
 
 import (
-	"errors"
+	"context"
+	"slices"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 // Validator is a function type used to define rules for validating usernames.
@@ -22,11 +24,16 @@ import (
 type Validator func(string) (bool, error)
 
 // Validate checks if the username in the Identity object is valid
-// according to the given validators of:
+// according to the validators in the Identity object, followed by
+// the given validators of:
 //
 //	type Validator func(string) (bool, error)
 //
-// If no validators are given, it uses the default ones in the Identity object.
+// The given validators apply to this call only; use AddValidator to
+// keep a validator for later calls.
+// It returns the error of the first failing validator; the built-in
+// validators return a *ValidationError that can be inspected with
+// errors.As or matched against sentinels such as ErrTooShort with errors.Is.
 //
 // Example usage:
 //
@@ -39,25 +46,56 @@ type Validator func(string) (bool, error)
 //	   return true, nil
 //	}
 //
-//	// Create a new Identity object
-//	// Use the WithValidator method to replace the default
-//	// validators with your own
-//	u := New("myUsername").WithValidator(myValidator)
+//	// Check the username once with an extra validator
+//	err := New("myUsername").Validate(myValidator)
 func (u *Identity) Validate(validators ...Validator) error {
+	e := u.engine()
 	if len(validators) > 0 {
-		u.validator = append(u.validator, validators...)
+		e.validator = append(slices.Clip(e.validator), validators...)
 	}
 
-	for _, f := range u.validator {
-		if ok, err := f(u.uname); !ok {
-			return err
-		}
+	return e.Validate(context.Background(), u.uname)
+}
+
+// ValidateAll checks the username in the Identity object against every
+// validator instead of stopping at the first failure, so all problems can
+// be reported at once. The given validators apply to this call only and
+// run after the ones in the Identity object.
+//
+// The failures are combined with errors.Join; nil is returned when all
+// validators pass. Individual failures can be matched with errors.Is and
+// errors.As, or listed by unwrapping the result:
+//
+//	err := New("ad!").ValidateAll()
+//	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+//		for _, e := range errs.Unwrap() {
+//			fmt.Println(e)
+//		}
+//	}
+//
+// Validate remains the fail-fast alternative for hot paths.
+func (u *Identity) ValidateAll(validators ...Validator) error {
+	e := u.engine()
+	if len(validators) > 0 {
+		e.validator = append(slices.Clip(e.validator), validators...)
 	}
-	return nil
+
+	return e.ValidateAll(context.Background(), u.uname)
+}
+
+// AddValidator appends validators to the Identity object, so they
+// apply to every later call of Validate, ValidateAll and Suggest.
+//
+// Example usage:
+//
+//	u := New("myUsername").AddValidator(myAvailabilityCheck)
+func (u *Identity) AddValidator(validators ...Validator) *Identity {
+	u.validator = append(u.validator, validators...)
+	return u
 }
 
 // WithValidator replaces the existing validators in the Identity
-// object with new ones.
+// object with new ones, including any context-aware validators.
 //
 // Example usage:
 //
@@ -76,31 +114,10 @@ func (u *Identity) Validate(validators ...Validator) error {
 //	u := New("myUsername").WithValidator(myValidator)
 func (u *Identity) WithValidator(validators ...Validator) *Identity {
 	u.validator = validators
+	u.validatorCtx = nil
 	return u
 }
 
-// isValid checks if the provided suggestion is valid.
-// A suggestion is considered valid if it passes all validators
-// and is not symmetric to the current username.
-//
-// If no validators are set, it applies the default validators.
-//
-// Returns:
-//   - true if the suggestion is valid.
-//   - false otherwise.
-func (u *Identity) isValid(suggestion string) bool {
-	if len(u.validator) <= 0 {
-		u.validator = defaultValidator()
-	}
-
-	for _, f := range u.validator {
-		if ok, _ := f(suggestion); !ok || u.isSymmetric(suggestion) {
-			return false
-		}
-	}
-	return true
-}
-
 // isSymmetric checks if the given suggestion is the same
 // as the current username. This ensures that suggestions
 // are not identical to the original username.
@@ -112,60 +129,107 @@ func (u *Identity) isSymmetric(suggestion string) bool {
 	return u.uname == suggestion
 }
 
-// validateRange checks if the input username meets the length requirements.
-// A valid username must be between 5 and 30 characters.
+// validateRange checks if the input username meets the length
+// requirements of the policy. With the default policy a valid
+// username must be between 5 and 30 characters.
 //
 // Returns:
 //   - true if the username is within the valid length range.
-//   - false and an error message otherwise.
-func validateRange(input string) (bool, error) {
+//   - false and a *ValidationError with CodeEmpty, CodeTooShort
+//     or CodeTooLong otherwise.
+func (p Policy) validateRange(input string) (bool, error) {
 	// Check if the username is empty
 	if input == "" {
-		return false, errors.New("username cannot be empty")
+		return false, &ValidationError{
+			Rule: RuleRange, Code: CodeEmpty, Pos: -1,
+			Min: p.MinLength, Max: p.MaxLength,
+		}
 	}
 
 	// Check if the username is too long or too short
-	if len(input) < 5 || len(input) > 30 {
-		return false, errors.New("username must be between 5 and 30 characters")
+	n := len(input)
+	if p.Unicode {
+		n = graphemeLen(input)
+	}
+	if n < p.MinLength || n > p.MaxLength {
+		code := CodeTooShort
+		if n > p.MaxLength {
+			code = CodeTooLong
+		}
+		return false, &ValidationError{
+			Rule: RuleRange, Code: code, Pos: -1,
+			Min: p.MinLength, Max: p.MaxLength,
+		}
 	}
 
 	return true, nil
 }
 
-// validateFormat ensures that the input username follows the allowed format.
-// Valid usernames can only contain letters, numbers, and one period ('.').
-// Usernames cannot start or end with a period.
+// validateFormat ensures that the input username follows the format
+// allowed by the policy. With the default policy valid usernames can
+// only contain letters, numbers, and one period ('.'), cannot start
+// or end with a period, nor consist of digits only.
 //
 // Returns:
 //   - true if the username matches the allowed format.
-//   - false and an error message otherwise.
-func validateFormat(input string) (bool, error) {
-	var err = "usernames can only contain letters, numbers, and period"
-	const dotChar = '.'
-	if input == "" || input[0] == dotChar || input[len(input)-1] == dotChar {
-		return false, errors.New(err)
+//   - false and a *ValidationError describing the offending
+//     character or rule otherwise.
+func (p Policy) validateFormat(input string) (bool, error) {
+	if input == "" {
+		return false, &ValidationError{Rule: RuleFormat, Code: CodeEmpty, Pos: -1}
+	}
+	if c := input[0]; p.isSeparator(c) && !p.AllowLeadingSeparator {
+		return false, &ValidationError{
+			Rule: RuleFormat, Code: CodeLeadingSep, Pos: 0, Char: rune(c),
+		}
 	}
-	const limitSpecialCharacters = 1
+	if last := len(input) - 1; p.isSeparator(input[last]) && !p.AllowTrailingSeparator {
+		return false, &ValidationError{
+			Rule: RuleFormat, Code: CodeTrailingSep, Pos: last, Char: rune(input[last]),
+		}
+	}
+
+	if p.Unicode {
+		return p.validateFormatUnicode(input)
+	}
+
 	var countSpecialCharacters int
 	var countDigit int
-	for _, c := range []byte(input) {
+	var previousSeparator bool
+	for i, c := range []byte(input) {
 
-		if c == dotChar && countSpecialCharacters < limitSpecialCharacters {
+		if p.isSeparator(c) {
 			countSpecialCharacters++
+			if p.MaxSeparators >= 0 && countSpecialCharacters > p.MaxSeparators {
+				return false, &ValidationError{
+					Rule: RuleFormat, Code: CodeTooManySeps, Pos: i, Char: rune(c),
+					Max: p.MaxSeparators,
+				}
+			}
+			if previousSeparator && !p.AllowConsecutiveSeparators {
+				return false, &ValidationError{
+					Rule: RuleFormat, Code: CodeConsecutiveSeps, Pos: i, Char: rune(c),
+				}
+			}
+			previousSeparator = true
 			continue
 		}
+		previousSeparator = false
 
 		if !isLetter(c) {
 			if isDigit(c) {
 				countDigit++
 			} else {
-				return false, errors.New(err)
+				r, _ := utf8.DecodeRuneInString(input[i:])
+				return false, &ValidationError{
+					Rule: RuleFormat, Code: CodeBadChar, Pos: i, Char: r,
+				}
 			}
 		}
 	}
 
-	if !(countDigit != len(input)-countSpecialCharacters) {
-		return false, errors.New(err)
+	if !p.AllowDigitsOnly && countDigit == len(input)-countSpecialCharacters {
+		return false, &ValidationError{Rule: RuleFormat, Code: CodeDigitsOnly, Pos: -1}
 	}
 
 	return true, nil
@@ -177,19 +241,31 @@ func validateFormat(input string) (bool, error) {
 //
 // Returns:
 //   - true if the username is not in the blacklist.
-//   - false and an error message otherwise.
+//   - false and a *ValidationError with CodeBlacklisted otherwise.
 func validateIntegrity(str string) (bool, error) {
-	err := "username is too weak or common, please choose a different one"
+	ok, err := matchIntegrity(blacklist, str)
+	if ve, isVE := err.(*ValidationError); isVE {
+		ve.Category = builtinCategory[ve.Match]
+	}
+	return ok, err
+}
+
+// matchIntegrity looks the lowercased username up in list,
+// which must be sorted and lowercase.
+func matchIntegrity(list []string, str string) (bool, error) {
 	str = strings.ToLower(str)
-	index := sort.SearchStrings(blacklist, str)
-	if index < len(blacklist) && blacklist[index] == str {
-		return false, errors.New(err)
+	index := sort.SearchStrings(list, str)
+	if index < len(list) && list[index] == str {
+		return false, &ValidationError{
+			Rule: RuleIntegrity, Code: CodeBlacklisted, Pos: -1,
+			Normalized: str, Match: list[index],
+		}
 	}
 
 	return true, nil
 }
 
-// defaultValidator provides a default set of validators for username validation.
+// defaultValidator provides the validators of the default policy.
 // These include:
 //   - validateRange: Ensures the username length is valid.
 //   - validateFormat: Ensures the username follows the correct format.
@@ -198,9 +274,5 @@ func validateIntegrity(str string) (bool, error) {
 // Returns:
 //   - A slice of Validator functions representing the default validation rules.
 func defaultValidator() []Validator {
-	return []Validator{
-		validateRange,
-		validateFormat,
-		validateIntegrity,
-	}
+	return DefaultPolicy().Validators()
 }